@@ -3,7 +3,9 @@ package config
 import (
 	// "fmt"
 	"os"
-	// "strconv"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -14,6 +16,18 @@ type Config struct {
 	DBUser      string
 	DBPass      string
 	DBName      string
+	Issuer      string
+	// MupBaseURL je adresa mup-vehicles servisa (vlasnici i vozaci pri prijavi).
+	MupBaseURL   string
+	MupTimeoutMs int
+	// KeyRotation je zivotni vek kljuca za potpisivanje, KeyOverlap koliko
+	// se stari kljuc jos objavljuje posle rotacije.
+	KeyRotation time.Duration
+	KeyOverlap  time.Duration
+	// KeySecret sifruje privatne kljuceve u bazi koju dele svi servisi.
+	KeySecret string
+	// ServiceClients su client_id -> client_secret za servisne tokene.
+	ServiceClients map[string]string
 }
 
 func GetConfig() Config {
@@ -22,12 +36,54 @@ func GetConfig() Config {
 	// 	panic(fmt.Sprintf("Couldn't parse service port: %v", err))
 	// }
 
+	mup := os.Getenv("MUP_BASE_URL")
+	if mup == "" {
+		mup = "http://mup-vehicles-service:8081"
+	}
+
+	timeoutMs := 3000
+	if v := os.Getenv("MUP_TIMEOUT_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			timeoutMs = n
+		}
+	}
+
+	rotationHours := 24 * 30
+	if v := os.Getenv("KEY_ROTATION_HOURS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			rotationHours = n
+		}
+	}
+
+	overlapMinutes := 60
+	if v := os.Getenv("KEY_OVERLAP_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			overlapMinutes = n
+		}
+	}
+
+	// SERVICE_CLIENTS=traffic-police:tajna1,mup-vehicles:tajna2
+	clients := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv("SERVICE_CLIENTS"), ",") {
+		id, secret, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && id != "" && secret != "" {
+			clients[id] = secret
+		}
+	}
+
 	return Config{
-		DBHost:      os.Getenv("DB_HOST"),
-		DBUser:      os.Getenv("DB_USER"),
-		DBPass:      os.Getenv("DB_PASS"),
-		DBName:      os.Getenv("DB_NAME"),
-		ServiceHost: os.Getenv("AUTH_SERVICE_HOST"),
-		ServicePort: 8080,
+		DBHost:         os.Getenv("DB_HOST"),
+		DBUser:         os.Getenv("DB_USER"),
+		DBPass:         os.Getenv("DB_PASS"),
+		DBName:         os.Getenv("DB_NAME"),
+		ServiceHost:    os.Getenv("AUTH_SERVICE_HOST"),
+		ServicePort:    8080,
+		Issuer:         os.Getenv("ISSUER"),
+		MupBaseURL:     mup,
+		MupTimeoutMs:   timeoutMs,
+		KeyRotation:    time.Duration(rotationHours) * time.Hour,
+		KeyOverlap:     time.Duration(overlapMinutes) * time.Minute,
+		KeySecret:      os.Getenv("SIGNING_KEY_SECRET"),
+		ServiceClients: clients,
 	}
 }
@@ -1,6 +1,7 @@
 package data
 
 import (
+	"common/migrate"
 	"fmt"
 
 	"gorm.io/driver/postgres"
@@ -15,3 +16,8 @@ func InitDB(host, user, password, dbname string, port int) (*gorm.DB, error) {
 	}
 	return db, nil
 }
+
+// Migrator vraca runner za migracije auth servisa (vidi migrations.go).
+func Migrator(db *gorm.DB) migrate.Runner {
+	return migrate.New(db, "auth", migrations)
+}
@@ -0,0 +1,94 @@
+package data
+
+import "common/migrate"
+
+// Migracije se nikad ne menjaju posle objavljivanja; izmena seme je nova
+// verzija. IF NOT EXISTS omogucava da se baza napravljena ranijim
+// AutoMigrate-om preuzme bez gresaka.
+var migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "create_users",
+		// users tabelu deli traffic-police (policajci), vidi njegovu migraciju 3.
+		// Zato je Down ne brise: uklanja se samo kolona koju koristi samo auth,
+		// a zajednicke kolone i policajci ostaju.
+		Up: `
+CREATE TABLE IF NOT EXISTS users (
+	id         text PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	email      text NOT NULL,
+	password   text NOT NULL,
+	role       text NOT NULL
+);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS first_name text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_name  text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_active  boolean NOT NULL DEFAULT true;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+`,
+		Down: `ALTER TABLE users DROP COLUMN IF EXISTS is_active;`,
+	},
+	{
+		Version: 2,
+		Name:    "create_refresh_tokens",
+		Up: `
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+	id             text PRIMARY KEY,
+	created_at     timestamptz,
+	updated_at     timestamptz,
+	family_id      text NOT NULL,
+	user_id        text,
+	email          text NOT NULL,
+	role           text NOT NULL,
+	token_hash     text NOT NULL,
+	expires_at     timestamptz NOT NULL,
+	revoked_at     timestamptz,
+	replaced_by_id text
+);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_email ON refresh_tokens (email);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+`,
+		Down: `DROP TABLE IF EXISTS refresh_tokens;`,
+	},
+	{
+		Version: 3,
+		Name:    "create_signing_keys",
+		Up: `
+CREATE TABLE IF NOT EXISTS signing_keys (
+	kid        text PRIMARY KEY,
+	seed       bytea NOT NULL,
+	created_at timestamptz,
+	retired_at timestamptz
+);
+`,
+		Down: `DROP TABLE IF EXISTS signing_keys;`,
+	},
+	{
+		Version: 4,
+		Name:    "link_users_to_mup_owners",
+		Up: `
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mup_owner_id  text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mup_driver_id text NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_mup_owner_id ON users (mup_owner_id) WHERE mup_owner_id <> '';
+`,
+		Down: `
+DROP INDEX IF EXISTS idx_users_mup_owner_id;
+ALTER TABLE users DROP COLUMN IF EXISTS mup_driver_id;
+ALTER TABLE users DROP COLUMN IF EXISTS mup_owner_id;
+`,
+	},
+	{
+		Version: 5,
+		Name:    "add_users_mup_jmbg",
+		Up: `
+ALTER TABLE users ADD COLUMN IF NOT EXISTS mup_jmbg text NOT NULL DEFAULT '';
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_mup_jmbg ON users (mup_jmbg) WHERE mup_jmbg <> '';
+`,
+		Down: `
+DROP INDEX IF EXISTS idx_users_mup_jmbg;
+ALTER TABLE users DROP COLUMN IF EXISTS mup_jmbg;
+`,
+	},
+}
@@ -0,0 +1,16 @@
+package data
+
+import (
+	"regexp"
+	"testing"
+)
+
+// users tabelu deli traffic-police; nijedna auth migracija je ne sme obrisati.
+func TestMigrationsKeepSharedUsersTable(t *testing.T) {
+	drop := regexp.MustCompile(`(?i)DROP\s+TABLE\s+(IF\s+EXISTS\s+)?users\b`)
+	for _, m := range migrations {
+		if drop.MatchString(m.Up) || drop.MatchString(m.Down) {
+			t.Errorf("migration %d %s drops the shared users table", m.Version, m.Name)
+		}
+	}
+}
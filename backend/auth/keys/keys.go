@@ -0,0 +1,342 @@
+// Package keys upravlja Ed25519 kljucevima za potpisivanje tokena:
+// rotacija, objavljivanje JWKS-a i potpisivanje.
+package keys
+
+import (
+	"auth/types"
+	"common/jwtauth"
+	"context"
+	"crypto"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+)
+
+// rotationLockID serijalizuje rotaciju kad radi vise instanci auth servisa.
+const rotationLockID = 84_250_001
+
+const serviceTokenTTL = 5 * time.Minute
+
+type Manager struct {
+	DB     *gorm.DB
+	Issuer string
+	// RotateEvery je starost aktivnog kljuca posle koje se pravi novi.
+	RotateEvery time.Duration
+	// Overlap je koliko se penzionisani kljuc jos objavljuje; mora biti duze
+	// od trajanja access tokena.
+	Overlap time.Duration
+	// RefreshEvery je najcesce koliko se u bazi proverava da li je druga
+	// instanca rotirala kljuc.
+	RefreshEvery time.Duration
+
+	// aead sifruje seed-ove u bazi; kljuc ima samo auth servis, jer ostali
+	// servisi dele bazu i mogli bi da procitaju signing_keys.
+	aead cipher.AEAD
+
+	mu        sync.RWMutex
+	checkedAt time.Time
+	kid       string
+	private   ed25519.PrivateKey
+	public    map[string]ed25519.PublicKey
+	jwks      jwtauth.JWKS
+}
+
+// NewManager pravi menadzer cije se privatni kljucevi u bazi cuvaju
+// sifrovani kljucem izvedenim iz secret.
+func NewManager(db *gorm.DB, issuer, secret string, rotateEvery, overlap time.Duration) (*Manager, error) {
+	if secret == "" {
+		return nil, errors.New("signing key secret is required")
+	}
+	sum := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{
+		DB:           db,
+		Issuer:       issuer,
+		RotateEvery:  rotateEvery,
+		Overlap:      overlap,
+		RefreshEvery: 10 * time.Second,
+		aead:         aead,
+	}, nil
+}
+
+// seal sifruje seed; kid je vezan kao dodatni podatak, pa se sifrat ne moze
+// premestiti na drugi red.
+func (m *Manager) seal(kid string, seed []byte) ([]byte, error) {
+	nonce := make([]byte, m.aead.NonceSize(), m.aead.NonceSize()+len(seed)+m.aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return m.aead.Seal(nonce, nonce, seed, []byte(kid)), nil
+}
+
+func (m *Manager) open(kid string, sealed []byte) ([]byte, error) {
+	n := m.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, fmt.Errorf("signing key %s: sealed seed too short", kid)
+	}
+	seed, err := m.aead.Open(nil, sealed[:n], sealed[n:], []byte(kid))
+	if err != nil || len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key %s: cannot decrypt seed (wrong secret?)", kid)
+	}
+	return seed, nil
+}
+
+func newKid() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Rotate pravi novi kljuc ako aktivni ne postoji ili je stariji od
+// RotateEvery, brise kljuceve kojima je proslo preklapanje i ucitava ostale.
+func (m *Manager) Rotate(ctx context.Context) error {
+	now := time.Now()
+
+	err := m.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", rotationLockID).Error; err != nil {
+			return err
+		}
+
+		var active types.SigningKey
+		res := tx.Where("retired_at IS NULL").Order("created_at desc").Limit(1).Find(&active)
+		if res.Error != nil {
+			return res.Error
+		}
+
+		if res.RowsAffected == 0 || now.Sub(active.CreatedAt) >= m.RotateEvery {
+			_, priv, err := ed25519.GenerateKey(rand.Reader)
+			if err != nil {
+				return err
+			}
+			kid, err := newKid()
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&types.SigningKey{}).
+				Where("retired_at IS NULL").
+				Update("retired_at", now).Error; err != nil {
+				return err
+			}
+			sealed, err := m.seal(kid, priv.Seed())
+			if err != nil {
+				return err
+			}
+			if err := tx.Create(&types.SigningKey{Kid: kid, Seed: sealed, CreatedAt: now}).Error; err != nil {
+				return err
+			}
+			fmt.Printf("[AUTH] 🔑 New signing key %s\n", kid)
+		}
+
+		return tx.Where("retired_at < ?", now.Add(-m.Overlap)).Delete(&types.SigningKey{}).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	return m.load(ctx)
+}
+
+func (m *Manager) load(ctx context.Context) error {
+	now := time.Now()
+	var list []types.SigningKey
+	if err := m.DB.WithContext(ctx).Order("created_at desc").Find(&list).Error; err != nil {
+		return err
+	}
+
+	public := make(map[string]ed25519.PublicKey, len(list))
+	set := jwtauth.JWKS{Keys: make([]jwtauth.JWK, 0, len(list))}
+	var kid string
+	var private ed25519.PrivateKey
+
+	for _, k := range list {
+		seed, err := m.unseal(ctx, &k)
+		if err != nil {
+			return err
+		}
+		priv := ed25519.NewKeyFromSeed(seed)
+		pub := priv.Public().(ed25519.PublicKey)
+		public[k.Kid] = pub
+		set.Keys = append(set.Keys, jwtauth.NewJWK(k.Kid, pub))
+		if k.RetiredAt == nil && private == nil {
+			kid, private = k.Kid, priv
+		}
+	}
+	if private == nil {
+		return errors.New("no active signing key")
+	}
+
+	m.mu.Lock()
+	m.kid, m.private, m.public, m.jwks = kid, private, public, set
+	m.checkedAt = now
+	m.mu.Unlock()
+	return nil
+}
+
+// unseal vraca seed kljuca. Seed upisan pre sifrovanja (tacno SeedSize
+// bajtova, sifrat je uvek duzi) se odmah sifruje u bazi.
+func (m *Manager) unseal(ctx context.Context, k *types.SigningKey) ([]byte, error) {
+	if len(k.Seed) != ed25519.SeedSize {
+		return m.open(k.Kid, k.Seed)
+	}
+	sealed, err := m.seal(k.Kid, k.Seed)
+	if err != nil {
+		return nil, err
+	}
+	if err := m.DB.WithContext(ctx).Model(&types.SigningKey{}).
+		Where("kid = ? AND seed = ?", k.Kid, k.Seed).
+		Update("seed", sealed).Error; err != nil {
+		return nil, err
+	}
+	return k.Seed, nil
+}
+
+// refresh ponovo ucitava kljuceve kad se aktivni kid u bazi razlikuje od
+// ucitanog, tj. kad je druga instanca u medjuvremenu rotirala kljuc. Baza se
+// ne pita cesce od RefreshEvery (kao MinRefresh na strani JWKS-a), pa se do
+// tada potpisuje ucitanim kljucem, koji je jos objavljen tokom preklapanja.
+func (m *Manager) refresh(ctx context.Context) error {
+	m.mu.Lock()
+	if time.Since(m.checkedAt) < m.RefreshEvery {
+		m.mu.Unlock()
+		return nil
+	}
+	m.checkedAt = time.Now()
+	current := m.kid
+	m.mu.Unlock()
+
+	var kids []string
+	if err := m.DB.WithContext(ctx).Model(&types.SigningKey{}).
+		Where("retired_at IS NULL").Order("created_at desc").Limit(1).
+		Pluck("kid", &kids).Error; err != nil {
+		return err
+	}
+	if len(kids) == 0 || kids[0] == current {
+		return nil
+	}
+	return m.load(ctx)
+}
+
+// Run proverava rotaciju jednom na sat dok se ctx ne otkaze.
+func (m *Manager) Run(ctx context.Context) {
+	t := time.NewTicker(time.Hour)
+	defer t.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+			if err := m.Rotate(ctx); err != nil {
+				fmt.Printf("[AUTH] ❌ Key rotation failed: %v\n", err)
+			}
+		}
+	}
+}
+
+// Sign potpisuje claim-ove aktivnim kljucem i postavlja issuer. Ako baza
+// nije dostupna, potpisuje se poslednjim ucitanim kljucem.
+func (m *Manager) Sign(claims *jwtauth.Claims) (string, error) {
+	if err := m.refresh(context.Background()); err != nil {
+		fmt.Printf("[AUTH] ❌ Key reload failed: %v\n", err)
+	}
+
+	m.mu.RLock()
+	kid, private := m.kid, m.private
+	m.mu.RUnlock()
+	if private == nil {
+		return "", errors.New("no active signing key")
+	}
+
+	claims.Issuer = m.Issuer
+	tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	tok.Header["kid"] = kid
+	return tok.SignedString(private)
+}
+
+// Key omogucava auth servisu da proverava sopstvene tokene (jwtauth.KeySet).
+// Nepoznat kid moze biti kljuc koji je upravo napravila druga instanca.
+func (m *Manager) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	m.mu.RLock()
+	pub, ok := m.public[kid]
+	m.mu.RUnlock()
+	if ok {
+		return pub, nil
+	}
+
+	if err := m.refresh(ctx); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	if pub, ok = m.public[kid]; !ok {
+		return nil, jwtauth.ErrUnknownKey
+	}
+	return pub, nil
+}
+
+func (m *Manager) JWKS() jwtauth.JWKS {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.jwks
+}
+
+// ServiceToken izdaje kratkotrajan token sa ulogom SERVICE.
+func (m *Manager) ServiceToken(service string) (string, time.Time, error) {
+	now := time.Now()
+	exp := now.Add(serviceTokenTTL)
+	signed, err := m.Sign(&jwtauth.Claims{
+		Role: jwtauth.RoleService,
+		ID:   service,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   service,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(exp),
+		},
+	})
+	return signed, exp, err
+}
+
+// Source je jwtauth.TokenSource za pozive koje sam auth servis upucuje.
+func (m *Manager) Source(service string) jwtauth.TokenSource {
+	return localSource{m: m, service: service}
+}
+
+type localSource struct {
+	m       *Manager
+	service string
+}
+
+func (s localSource) Token(context.Context) (string, time.Time, error) {
+	return s.m.ServiceToken(s.service)
+}
+
+// WithKeysAPI objavljuje javne kljuceve.
+func WithKeysAPI(r *gin.RouterGroup, m *Manager) {
+	r.GET("/.well-known/jwks.json", func(c *gin.Context) {
+		if err := m.refresh(c.Request.Context()); err != nil {
+			fmt.Printf("[AUTH] ❌ Key reload failed: %v\n", err)
+		}
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, m.JWKS())
+	})
+}
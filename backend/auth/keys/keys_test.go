@@ -0,0 +1,115 @@
+package keys
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"testing"
+	"time"
+
+	"auth/types"
+	"common/dbtest"
+	"common/jwtauth"
+
+	"gorm.io/gorm"
+)
+
+func newTestManager(t *testing.T, db *gorm.DB, secret string) *Manager {
+	t.Helper()
+	m, err := NewManager(db, "test-auth", secret, 24*time.Hour, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+// addKey upisuje novi aktivni kljuc i penzionise prethodni, kao Rotate na
+// drugoj instanci. Bez m se seed upisuje nesifrovan, kao pre sifrovanja.
+func addKey(t *testing.T, db *gorm.DB, m *Manager, kid string, at time.Time) ed25519.PrivateKey {
+	t.Helper()
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seed := priv.Seed()
+	if m != nil {
+		if seed, err = m.seal(kid, seed); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Model(&types.SigningKey{}).Where("retired_at IS NULL").Update("retired_at", at).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&types.SigningKey{Kid: kid, Seed: seed, CreatedAt: at}).Error; err != nil {
+		t.Fatal(err)
+	}
+	return priv
+}
+
+func TestSignPicksUpRotationAfterRefreshEvery(t *testing.T) {
+	db := dbtest.Open(t, "", &types.SigningKey{})
+	now := time.Now()
+	m := newTestManager(t, db, "secret")
+	addKey(t, db, m, "k1", now.Add(-time.Hour))
+	if err := m.load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	// druga instanca je rotirala kljuc
+	addKey(t, db, m, "k2", now)
+	if _, err := m.Sign(&jwtauth.Claims{}); err != nil {
+		t.Fatal(err)
+	}
+	if m.kid != "k1" {
+		t.Fatalf("kid = %s right after load, want k1 until RefreshEvery passes", m.kid)
+	}
+
+	m.checkedAt = now.Add(-m.RefreshEvery)
+	if _, err := m.Sign(&jwtauth.Claims{}); err != nil {
+		t.Fatal(err)
+	}
+	if m.kid != "k2" {
+		t.Fatalf("kid = %s after RefreshEvery, want k2", m.kid)
+	}
+	if len(m.JWKS().Keys) != 2 {
+		t.Fatalf("JWKS has %d keys, want both during overlap", len(m.JWKS().Keys))
+	}
+}
+
+func TestSeedsAreSealed(t *testing.T) {
+	db := dbtest.Open(t, "", &types.SigningKey{})
+	m := newTestManager(t, db, "secret")
+	priv := addKey(t, db, nil, "old", time.Now())
+
+	// seed upisan pre sifrovanja se sifruje pri ucitavanju
+	if err := m.load(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if !m.private.Equal(priv) {
+		t.Fatal("loaded key differs from the stored one")
+	}
+	var k types.SigningKey
+	if err := db.First(&k, "kid = ?", "old").Error; err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(k.Seed, priv.Seed()) {
+		t.Fatal("seed is still stored in plaintext")
+	}
+	if seed, err := m.open("old", k.Seed); err != nil || !bytes.Equal(seed, priv.Seed()) {
+		t.Fatalf("open = %v, want the original seed", err)
+	}
+
+	// sifrat je vezan za kid
+	if _, err := m.open("other", k.Seed); err == nil {
+		t.Fatal("sealed seed opened under another kid")
+	}
+
+	// bez ispravne tajne kljucevi se ne ucitavaju
+	if err := newTestManager(t, db, "wrong").load(context.Background()); err == nil {
+		t.Fatal("load succeeded with the wrong secret")
+	}
+	if _, err := NewManager(db, "test-auth", "", time.Hour, time.Hour); err == nil {
+		t.Fatal("NewManager accepted an empty secret")
+	}
+}
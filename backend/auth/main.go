@@ -3,8 +3,14 @@ package main
 import (
 	"auth/config"
 	"auth/data"
+	"auth/keys"
 	"auth/user"
+	"common/jwtauth"
+	"common/policy"
+	"context"
 	"fmt"
+	"log"
+	"os"
 
 	"github.com/gin-gonic/gin"
 )
@@ -17,6 +23,27 @@ func main() {
 	if err != nil {
 		panic(fmt.Sprintf("Failed to connect to database: %v", err))
 	}
+	migrator := data.Migrator(db)
+
+	// "server migrate up|down [n]|status" samo izvrsava migracije i izlazi
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrator.Command(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if _, err = migrator.Up(context.Background()); err != nil {
+		panic(fmt.Sprintf("Failed to migrate database: %v", err))
+	}
+
+	km, err := keys.NewManager(db, cfg.Issuer, cfg.KeySecret, cfg.KeyRotation, cfg.KeyOverlap)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to set up signing keys: %v", err))
+	}
+	if err = km.Rotate(context.Background()); err != nil {
+		panic(fmt.Sprintf("Failed to load signing keys: %v", err))
+	}
+	go km.Run(context.Background())
 
 	gin.SetMode(gin.ReleaseMode)
 	router := gin.Default()
@@ -25,9 +52,20 @@ func main() {
 		panic("Error setting trusted proxies")
 	}
 
+	routes := routePolicy()
+	router.Use(
+		jwtauth.Middleware(jwtauth.Config{Issuer: cfg.Issuer, Keys: km}, routes.PublicPaths()...),
+		policy.Enforce(routes, nil),
+	)
+
 	api := router.Group("")
 
-	user.WithUserAPI(api, db)
+	keys.WithKeysAPI(api, km)
+	user.WithUserAPI(api, db, km, cfg)
+
+	if err := routes.Verify(router.Routes()); err != nil {
+		panic(err)
+	}
 
 	router.Run(fmt.Sprintf("0.0.0.0:%d", cfg.ServicePort))
 
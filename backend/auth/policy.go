@@ -0,0 +1,39 @@
+package main
+
+import (
+	"common/jwtauth"
+	"common/policy"
+
+	"github.com/gin-gonic/gin"
+)
+
+// routePolicy je tabela pristupa za sve rute auth servisa. Upravljanje
+// korisnicima je dozvoljeno samo MUP administratorima.
+func routePolicy() policy.Table {
+	const (
+		citizen = jwtauth.RoleCitizen
+		mup     = jwtauth.RoleMup
+		traffic = jwtauth.RoleTraffic
+	)
+
+	ownUser := func(c *gin.Context, claims *jwtauth.Claims) (bool, error) {
+		return claims.ID != "" && claims.ID == c.Param("id"), nil
+	}
+
+	return policy.Table{
+		"GET /.well-known/jwks.json": policy.Public,
+		"POST /users":                policy.Public,
+		"POST /login":                policy.Public,
+		"POST /refresh":              policy.Public,
+		"POST /logout":               policy.Public,
+		"POST /token":                policy.Public,
+
+		"GET /me": policy.Allow(citizen, mup, traffic),
+
+		"GET /users":                  policy.Allow(mup),
+		"GET /users/:id":              policy.Allow(mup).OrSelf(ownUser, citizen, traffic),
+		"PATCH /users/:id/role":       policy.Allow(mup),
+		"PATCH /users/:id/deactivate": policy.Allow(mup),
+		"PATCH /users/:id/activate":   policy.Allow(mup),
+	}
+}
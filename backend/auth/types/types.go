@@ -1,8 +1,11 @@
 package types
 
 import (
+	"common/mupclient"
 	"math/rand"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type BaseModel struct {
@@ -20,13 +23,42 @@ func generateID(n int) string {
 	return string(s)
 }
 
+func (b *BaseModel) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == "" {
+		b.ID = generateID(10)
+	}
+	return nil
+}
+
 type User struct {
 	BaseModel        // ima ID string + BeforeCreate koji generiše random ID
 	Email     string `gorm:"unique;not null" json:"email"`
-	Password  string `gorm:"not null" json:"password"`
+	Password  string `gorm:"not null" json:"-"` // bcrypt hash, nikad u odgovoru
 	FirstName string `gorm:"not null" json:"firstName"`
 	LastName  string `gorm:"not null" json:"lastName"`
 	Role      Role   `gorm:"not null" json:"role"`
+	IsActive  bool   `gorm:"not null;default:true" json:"isActive"`
+	// Federisani nalozi (prvi login preko MUP-a) pamte vezu sa MUP
+	// vlasnikom; lozinku za njih uvek proverava MUP, Password ostaje prazan.
+	MupOwnerID  string `gorm:"not null;default:''" json:"mupOwnerId,omitempty"`
+	MupJMBG     string `gorm:"column:mup_jmbg;not null;default:''" json:"mupJmbg,omitempty"`
+	MupDriverID string `gorm:"not null;default:''" json:"mupDriverId,omitempty"`
+}
+
+// Federated vraca true za naloge povezane sa MUP vlasnikom.
+func (u *User) Federated() bool {
+	return u.MupOwnerID != ""
+}
+
+type RegisterReq struct {
+	Email     string `json:"email"`
+	Password  string `json:"password"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+type SetRoleReq struct {
+	Role Role `json:"role"`
 }
 type Role string
 
@@ -36,42 +68,61 @@ const (
 	RoleTraffic Role = "TRAFFIC"
 )
 
+func (r Role) Valid() bool {
+	return r == RoleCitizen || r == RoleMup || r == RoleTraffic
+}
+
 type LoginReq struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 type LoginResp struct {
-	Role        string `json:"role"`
-	AccessToken string `json:"access_token"`
-	ExpiresIn   int64  `json:"expires_in"`
-	TokenType   string `json:"token_type"`
+	Role             string `json:"role"`
+	AccessToken      string `json:"access_token"`
+	ExpiresIn        int64  `json:"expires_in"`
+	TokenType        string `json:"token_type"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresIn int64  `json:"refresh_expires_in"`
 }
 
-type MupDriver struct {
-	ID                      string   `json:"id"`
-	IsSuspended             bool     `json:"isSuspended"`
-	NumberOfViolationPoints int      `json:"numberOfViolationPoints"`
-	Picture                 string   `json:"picture"`
-	Owner                   MupOwner `json:"owner"`
+type RefreshReq struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
-type MupOwner struct {
-	ID        string `json:"id"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Address   string `json:"address"`
-	JMBG      string `json:"jmbg"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
+// RefreshToken je jedna sesija u lancu rotacija. U bazi se cuva samo hash
+// tokena; svi tokeni nastali rotacijom istog logina dele FamilyID.
+type RefreshToken struct {
+	BaseModel
+	FamilyID     string     `gorm:"index;not null" json:"familyId"`
+	UserID       string     `gorm:"index" json:"userId"`
+	Email        string     `gorm:"index;not null" json:"email"`
+	Role         Role       `gorm:"not null" json:"role"`
+	TokenHash    string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt    time.Time  `gorm:"not null" json:"expiresAt"`
+	RevokedAt    *time.Time `json:"revokedAt,omitempty"`
+	ReplacedByID string     `json:"replacedById,omitempty"`
+}
+
+// MeResp je profil ulogovanog korisnika: lokalni nalog i, za povezane
+// gradjane, vozacka iz MUP-a (nil ako je nema ili MUP nije dostupan).
+type MeResp struct {
+	User   User              `json:"user"`
+	Driver *mupclient.Driver `json:"driver"`
+}
+
+// SigningKey je Ed25519 kljuc za potpisivanje tokena. Kljuc bez RetiredAt je
+// aktivan; penzionisani kljuc se jos neko vreme objavljuje u JWKS-u da bi
+// tokeni potpisani njime vazili do isteka.
+type SigningKey struct {
+	Kid string `gorm:"primaryKey;type:text" json:"kid"`
+	// Seed je Ed25519 seed sifrovan AES-GCM-om (nonce ispred sifrata) kljucem
+	// iz SIGNING_KEY_SECRET, koji ostali servisi nemaju.
+	Seed      []byte     `gorm:"not null" json:"-"`
+	CreatedAt time.Time  `json:"createdAt"`
+	RetiredAt *time.Time `json:"retiredAt,omitempty"`
 }
 
-type MupVehicle struct {
-	ID           string   `json:"id"`
-	Mark         string   `json:"mark"`
-	Model        string   `json:"model"`
-	Registration string   `json:"registration"`
-	Year         int      `json:"year"`
-	Color        string   `json:"color"`
-	IsStolen     bool     `json:"isStolen"`
-	Owner        MupOwner `json:"owner"`
+type ServiceTokenReq struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
 }
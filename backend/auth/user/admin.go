@@ -0,0 +1,147 @@
+package user
+
+import (
+	"auth/types"
+	"common/jwtauth"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+func pageParams(c *gin.Context) (page, size int) {
+	page, _ = strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ = strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(defaultPageSize)))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > maxPageSize {
+		size = defaultPageSize
+	}
+	return page, size
+}
+
+// listUsers vraca stranicu korisnika; ukupan broj ide u X-Total-Count da bi
+// telo ostalo obican niz (tako ga ocekuje UsersRolesPage).
+func listUsers(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		page, size := pageParams(c)
+
+		q := db.WithContext(c.Request.Context()).Model(&types.User{})
+		if search := strings.TrimSpace(c.Query("q")); search != "" {
+			like := "%" + search + "%"
+			q = q.Where("email ILIKE ? OR first_name ILIKE ? OR last_name ILIKE ? OR (first_name || ' ' || last_name) ILIKE ?",
+				like, like, like, like)
+		}
+		if role := c.Query("role"); role != "" {
+			q = q.Where("role = ?", role)
+		}
+
+		var total int64
+		if err := q.Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		users := []types.User{}
+		if err := q.Order("email").Offset((page - 1) * size).Limit(size).Find(&users).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+		c.Header("X-Page", strconv.Itoa(page))
+		c.Header("X-Page-Size", strconv.Itoa(size))
+		c.JSON(http.StatusOK, users)
+	}
+}
+
+func findUser(c *gin.Context, db *gorm.DB) (*types.User, bool) {
+	var u types.User
+	if err := db.WithContext(c.Request.Context()).First(&u, "id = ?", c.Param("id")).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+		} else {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		}
+		return nil, false
+	}
+	return &u, true
+}
+
+func getUser(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, ok := findUser(c, db)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, u)
+	}
+}
+
+func setUserRole(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.SetRoleReq
+		if err := c.ShouldBindJSON(&req); err != nil || !req.Role.Valid() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "role must be one of CITIZEN, MUP, TRAFFIC"})
+			return
+		}
+
+		u, ok := findUser(c, db)
+		if !ok {
+			return
+		}
+		if claims, _ := jwtauth.FromContext(c); claims != nil && claims.ID == u.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "cannot change own role"})
+			return
+		}
+
+		u.Role = req.Role
+		if err := db.WithContext(c.Request.Context()).Model(u).Update("role", u.Role).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		c.JSON(http.StatusOK, u)
+	}
+}
+
+// setUserActive (de)aktivira nalog. Deaktivacija opoziva i sve refresh
+// tokene, tako da korisnik ostaje bez pristupa cim mu istekne access token.
+func setUserActive(db *gorm.DB, active bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		u, ok := findUser(c, db)
+		if !ok {
+			return
+		}
+		if claims, _ := jwtauth.FromContext(c); !active && claims != nil && claims.ID == u.ID {
+			c.JSON(http.StatusConflict, gin.H{"error": "cannot deactivate own account"})
+			return
+		}
+
+		err := db.WithContext(c.Request.Context()).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Model(u).Update("is_active", active).Error; err != nil {
+				return err
+			}
+			if !active {
+				return revokeAllSessions(tx, u.Email, time.Now())
+			}
+			return nil
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		u.IsActive = active
+		c.JSON(http.StatusOK, u)
+	}
+}
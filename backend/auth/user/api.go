@@ -1,21 +1,34 @@
 package user
 
 import (
+	"auth/config"
+	"auth/keys"
+	"common/jwtauth"
+	"common/mupclient"
 	"net/http"
-	"os"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 )
 
-func WithUserAPI(r *gin.RouterGroup, db *gorm.DB) {
-	secret := []byte(os.Getenv("JWT_SECRET"))
-	issuer := os.Getenv("ISSUER")
-	mupBaseURL := "http://mup-vehicles-service:8081"
+func WithUserAPI(r *gin.RouterGroup, db *gorm.DB, km *keys.Manager, cfg config.Config) {
+	mup := mupclient.New(cfg.MupBaseURL, &http.Client{
+		Timeout:   time.Duration(cfg.MupTimeoutMs) * time.Millisecond,
+		Transport: jwtauth.NewServiceTransport(km.Source("auth")),
+	})
 
-	httpClient := &http.Client{Timeout: 3 * time.Second}
+	r.POST("/users", createUser(db, mup))
+	r.POST("/login", login(db, km, mup))
+	r.POST("/refresh", refresh(db, km))
+	r.POST("/logout", logout(db))
+	r.POST("/token", serviceToken(km, cfg.ServiceClients))
 
-	r.POST("/users", createUser(db))
-	r.POST("/login", login(db, issuer, secret, httpClient, mupBaseURL))
+	r.GET("/me", me(db, mup))
+
+	r.GET("/users", listUsers(db))
+	r.GET("/users/:id", getUser(db))
+	r.PATCH("/users/:id/role", setUserRole(db))
+	r.PATCH("/users/:id/deactivate", setUserActive(db, false))
+	r.PATCH("/users/:id/activate", setUserActive(db, true))
 }
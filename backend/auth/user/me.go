@@ -0,0 +1,45 @@
+package user
+
+import (
+	"auth/types"
+	"common/jwtauth"
+	"common/mupclient"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// me vraca profil ulogovanog korisnika. Za povezane gradjane dodaje i
+// vozacku iz MUP-a; ako MUP ne odgovori, profil se vraca bez nje.
+func me(db *gorm.DB, mup *mupclient.Client) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, ok := jwtauth.FromContext(c)
+		if !ok {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+			return
+		}
+
+		var u types.User
+		if err := db.WithContext(c.Request.Context()).First(&u, "id = ?", claims.ID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusNotFound, gin.H{"error": "user not found"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			}
+			return
+		}
+
+		resp := types.MeResp{User: u}
+		if u.MupDriverID != "" {
+			driver, err := mup.GetDriver(c.Request.Context(), u.MupDriverID)
+			if err != nil && !errors.Is(err, mupclient.ErrNotFound) {
+				fmt.Printf("[AUTH] ❌ MUP driver lookup failed: %v\n", err)
+			}
+			resp.Driver = driver
+		}
+		c.JSON(http.StatusOK, resp)
+	}
+}
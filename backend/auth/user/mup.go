@@ -0,0 +1,79 @@
+package user
+
+import (
+	"auth/types"
+	"common/mupclient"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// errInvalidCredentials znaci da je MUP odbio email/lozinku; sve ostale
+// greske iz mupLogin znace da MUP nije dostupan.
+var errInvalidCredentials = errors.New("invalid credentials")
+
+// mupLogin proverava kredencijale preko MUP-ovog POST /login. Prihvataju se
+// samo vlasnici (gradjani), ne i MUP administratori.
+func mupLogin(ctx context.Context, mup *mupclient.Client, email, password string) (*mupclient.Identity, error) {
+	id, err := mup.Login(ctx, email, password)
+	if errors.Is(err, mupclient.ErrUnauthorized) {
+		return nil, errInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+	if id.Kind != mupclient.KindOwner || id.ID == "" {
+		return nil, errInvalidCredentials
+	}
+	return id, nil
+}
+
+// provisionMupUser pravi lokalni nalog vezan za MUP vlasnika pri prvom
+// loginu, a postojecem federisanom nalogu osvezava ime i vozacku.
+func provisionMupUser(db *gorm.DB, existing *types.User, id *mupclient.Identity, email string) (*types.User, error) {
+	if existing != nil {
+		if existing.MupOwnerID != id.ID {
+			return nil, errInvalidCredentials
+		}
+		updates := map[string]any{
+			"first_name":    id.FirstName,
+			"last_name":     id.LastName,
+			"mup_jmbg":      id.JMBG,
+			"mup_driver_id": id.DriverID,
+		}
+		if err := db.Model(existing).Updates(updates).Error; err != nil {
+			return nil, err
+		}
+		existing.FirstName, existing.LastName = id.FirstName, id.LastName
+		existing.MupJMBG, existing.MupDriverID = id.JMBG, id.DriverID
+		return existing, nil
+	}
+
+	u := types.User{
+		Email:       email,
+		FirstName:   id.FirstName,
+		LastName:    id.LastName,
+		Role:        types.RoleCitizen,
+		IsActive:    true,
+		MupOwnerID:  id.ID,
+		MupJMBG:     id.JMBG,
+		MupDriverID: id.DriverID,
+	}
+	if err := db.Create(&u).Error; err != nil {
+		// paralelni prvi login istog korisnika: nalog je vec napravljen
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			again, gErr := getUserByEmail(db, email)
+			if gErr != nil || again == nil || again.MupOwnerID != id.ID {
+				return nil, err
+			}
+			return again, nil
+		}
+		return nil, err
+	}
+	fmt.Printf("[AUTH] ✅ Provisioned MUP user %s (owner=%s)\n", email, id.ID)
+	return &u, nil
+}
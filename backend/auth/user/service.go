@@ -1,44 +1,20 @@
 package user
 
 import (
+	"auth/keys"
 	"auth/types"
-	"encoding/json"
+	"common/mupclient"
 	"errors"
 	"fmt"
 	"net/http"
 	"strings"
-	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-// same generic helper as traffic service
-func mupGet[T any](client *http.Client, baseURL, path string) (*T, int, error) {
-	req, err := http.NewRequest("GET", baseURL+path, nil)
-	if err != nil {
-		return nil, 0, err
-	}
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, 0, err
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		return nil, res.StatusCode, nil
-	}
-
-	var out T
-	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
-		return nil, res.StatusCode, err
-	}
-	return &out, res.StatusCode, nil
-}
-
 func getUserByEmail(db *gorm.DB, email string) (*types.User, error) {
 	var u types.User
 	result := db.Where("email = ?", email).Limit(1).Find(&u)
@@ -51,9 +27,11 @@ func getUserByEmail(db *gorm.DB, email string) (*types.User, error) {
 	return &u, nil
 }
 
-func createUser(db *gorm.DB) gin.HandlerFunc {
+// createUser registruje lokalni nalog. Email MUP vlasnika se odbija: takav
+// korisnik se prijavljuje MUP lozinkom i nalog mu nastaje pri prvom loginu.
+func createUser(db *gorm.DB, mup *mupclient.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		var in types.User
+		var in types.RegisterReq
 		if err := c.ShouldBindJSON(&in); err != nil {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid payload", "details": err.Error()})
 			return
@@ -65,6 +43,15 @@ func createUser(db *gorm.DB) gin.HandlerFunc {
 			return
 		}
 
+		if _, err := mup.GetOwnerByEmail(c.Request.Context(), in.Email); err == nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "email belongs to an MUP account, sign in with the MUP password"})
+			return
+		} else if !errors.Is(err, mupclient.ErrNotFound) {
+			fmt.Printf("[AUTH] ❌ MUP owner lookup failed: %v\n", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "identity provider unavailable"})
+			return
+		}
+
 		hash, err := bcrypt.GenerateFromPassword([]byte(in.Password), bcrypt.DefaultCost)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to hash password"})
@@ -77,6 +64,7 @@ func createUser(db *gorm.DB) gin.HandlerFunc {
 			FirstName: in.FirstName,
 			LastName:  in.LastName,
 			Role:      types.RoleCitizen,
+			IsActive:  true,
 		}
 
 		if err := db.WithContext(c.Request.Context()).Create(&u).Error; err != nil {
@@ -93,7 +81,7 @@ func createUser(db *gorm.DB) gin.HandlerFunc {
 	}
 }
 
-func login(db *gorm.DB, issuer string, secret []byte, httpClient *http.Client, mupBaseURL string) gin.HandlerFunc {
+func login(db *gorm.DB, km *keys.Manager, mup *mupclient.Client) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		var req types.LoginReq
 		if err := c.ShouldBindJSON(&req); err != nil {
@@ -109,78 +97,52 @@ func login(db *gorm.DB, issuer string, secret []byte, httpClient *http.Client, m
 			return
 		}
 
-		var finalUser *types.User
-		var role types.Role
+		ctx := c.Request.Context()
 
-		// Step 1: try local DB
-		localUser, err := getUserByEmail(db, email)
+		// Step 1: lokalni nalog sa sopstvenom lozinkom
+		localUser, err := getUserByEmail(db.WithContext(ctx), email)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
 			return
 		}
 
-		if localUser != nil {
+		var finalUser *types.User
+		if localUser != nil && !localUser.Federated() {
 			if err := bcrypt.CompareHashAndPassword([]byte(localUser.Password), []byte(password)); err != nil {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 				return
 			}
 			finalUser = localUser
-			role = localUser.Role
-
 		} else {
-			fmt.Printf("[AUTH] User not in local DB, calling MUP: %s\n", email)
-
-			mupDriver, dSt, mupErr := mupGet[types.MupDriver](httpClient, mupBaseURL, "/drivers/email/"+email)
-			fmt.Printf("[AUTH] MUP response — status: %d, err: %v, driver: %v\n", dSt, mupErr, mupDriver != nil)
-
-			if mupErr != nil || dSt != http.StatusOK || mupDriver == nil {
-				fmt.Printf("[AUTH] ❌ MUP lookup failed\n")
+			// Step 2: federisani login, kredencijale proverava MUP
+			id, err := mupLogin(ctx, mup, email, password)
+			if errors.Is(err, errInvalidCredentials) {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 				return
 			}
+			if err != nil {
+				fmt.Printf("[AUTH] ❌ MUP login failed: %v\n", err)
+				c.JSON(http.StatusServiceUnavailable, gin.H{"error": "identity provider unavailable"})
+				return
+			}
 
-			if password != "123" {
-				fmt.Printf("[AUTH] ❌ Password mismatch for MUP user\n")
+			finalUser, err = provisionMupUser(db.WithContext(ctx), localUser, id, email)
+			if errors.Is(err, errInvalidCredentials) {
 				c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid credentials"})
 				return
 			}
-
-			fmt.Printf("[AUTH] ✅ MUP login success: %s\n", mupDriver.Owner.Email)
-
-			finalUser = &types.User{
-				Email:     email,
-				FirstName: mupDriver.Owner.FirstName,
-				LastName:  mupDriver.Owner.LastName,
-				Role:      types.RoleCitizen,
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+				return
 			}
-			role = types.RoleCitizen
 		}
 
-		// Step 3: issue JWT
-		now := time.Now()
-		exp := now.Add(15 * time.Minute)
-
-		claims := jwt.MapClaims{
-			"sub":   finalUser.Email,
-			"iss":   issuer,
-			"role":  role,
-			"id":    finalUser.ID,
-			"email": finalUser.Email,
-			"iat":   now.Unix(),
-			"exp":   exp.Unix(),
-		}
-
-		tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-		signed, err := tok.SignedString(secret)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "signing failed"})
+		if !finalUser.IsActive {
+			c.JSON(http.StatusForbidden, gin.H{"error": "account is deactivated"})
 			return
 		}
 
-		c.JSON(http.StatusOK, types.LoginResp{
-			AccessToken: signed,
-			ExpiresIn:   int64(15 * 60),
-			TokenType:   "Bearer",
-		})
+		// Step 3: issue JWT + refresh token
+		startSession(c, db, km, sessionFor(finalUser))
 	}
 }
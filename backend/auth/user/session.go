@@ -0,0 +1,297 @@
+package user
+
+import (
+	"auth/keys"
+	"auth/types"
+	"common/jwtauth"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 7 * 24 * time.Hour
+)
+
+// session je ono sto ulazi u access token. Refresh token pamti samo
+// UserID/Email/Role; veze sa MUP-om se pri rotaciji citaju iz users.
+type session struct {
+	UserID   string
+	Email    string
+	Role     types.Role
+	OwnerID  string
+	JMBG     string
+	DriverID string
+}
+
+func sessionFor(u *types.User) session {
+	return session{
+		UserID:   u.ID,
+		Email:    u.Email,
+		Role:     u.Role,
+		OwnerID:  u.MupOwnerID,
+		JMBG:     u.MupJMBG,
+		DriverID: u.MupDriverID,
+	}
+}
+
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
+
+func newRawToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func signAccessToken(km *keys.Manager, s session, now time.Time) (string, error) {
+	return km.Sign(&jwtauth.Claims{
+		Role:     string(s.Role),
+		ID:       s.UserID,
+		Email:    s.Email,
+		OwnerID:  s.OwnerID,
+		JMBG:     s.JMBG,
+		DriverID: s.DriverID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   s.Email,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
+		},
+	})
+}
+
+// createRefreshToken upisuje novi refresh token u datu familiju i vraca
+// sirovi token (koji se nigde ne cuva).
+func createRefreshToken(tx *gorm.DB, familyID string, s session, now time.Time) (string, *types.RefreshToken, error) {
+	raw, err := newRawToken()
+	if err != nil {
+		return "", nil, err
+	}
+	if familyID == "" {
+		// nova familija (login)
+		familyID = hashToken(raw)[:16]
+	}
+	rt := &types.RefreshToken{
+		FamilyID:  familyID,
+		UserID:    s.UserID,
+		Email:     s.Email,
+		Role:      s.Role,
+		TokenHash: hashToken(raw),
+		ExpiresAt: now.Add(refreshTokenTTL),
+	}
+	if err := tx.Create(rt).Error; err != nil {
+		return "", nil, err
+	}
+	return raw, rt, nil
+}
+
+// startSession izdaje access token i refresh token iz nove familije (login).
+func startSession(c *gin.Context, db *gorm.DB, km *keys.Manager, s session) {
+	now := time.Now()
+	raw, _, err := createRefreshToken(db.WithContext(c.Request.Context()), "", s, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+		return
+	}
+	respondWithTokens(c, km, s, raw, now)
+}
+
+func respondWithTokens(c *gin.Context, km *keys.Manager, s session, rawRefresh string, now time.Time) {
+	signed, err := signAccessToken(km, s, now)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "signing failed"})
+		return
+	}
+
+	c.JSON(http.StatusOK, types.LoginResp{
+		Role:             string(s.Role),
+		AccessToken:      signed,
+		ExpiresIn:        int64(accessTokenTTL.Seconds()),
+		TokenType:        "Bearer",
+		RefreshToken:     rawRefresh,
+		RefreshExpiresIn: int64(refreshTokenTTL.Seconds()),
+	})
+}
+
+func revokeFamily(tx *gorm.DB, familyID string, now time.Time) error {
+	return tx.Model(&types.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", now).Error
+}
+
+func revokeAllSessions(tx *gorm.DB, email string, now time.Time) error {
+	return tx.Model(&types.RefreshToken{}).
+		Where("email = ? AND revoked_at IS NULL", email).
+		Update("revoked_at", now).Error
+}
+
+var (
+	errInvalidRefresh = errors.New("invalid refresh token")
+	errRefreshReused  = errors.New("refresh token reuse detected")
+)
+
+// rotate menja refresh token novim iz iste familije. Ako je token vec bio
+// rotiran, neko ga je ponovo poslao (kradja) pa se gase sve sesije korisnika.
+func rotate(db *gorm.DB, raw string, now time.Time) (session, string, error) {
+	var (
+		s      session
+		newRaw string
+		reused bool
+	)
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var rt types.RefreshToken
+		res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("token_hash = ?", hashToken(raw)).Limit(1).Find(&rt)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errInvalidRefresh
+		}
+
+		if rt.RevokedAt != nil {
+			if rt.ReplacedByID != "" {
+				// opoziv mora da se commit-uje, zato se ovde ne vraca greska
+				reused = true
+				return revokeAllSessions(tx, rt.Email, now)
+			}
+			return errInvalidRefresh
+		}
+		if now.After(rt.ExpiresAt) {
+			return errInvalidRefresh
+		}
+
+		s = session{UserID: rt.UserID, Email: rt.Email, Role: rt.Role}
+		// uzmi aktuelnu ulogu i veze sa MUP-om iz baze
+		u, err := getUserByEmail(tx, rt.Email)
+		if err != nil {
+			return err
+		}
+		if u != nil {
+			if !u.IsActive {
+				return errInvalidRefresh
+			}
+			s = sessionFor(u)
+		}
+
+		nextRaw, next, err := createRefreshToken(tx, rt.FamilyID, s, now)
+		if err != nil {
+			return err
+		}
+		newRaw = nextRaw
+
+		return tx.Model(&rt).Updates(map[string]any{
+			"revoked_at":     now,
+			"replaced_by_id": next.ID,
+		}).Error
+	})
+	if err != nil {
+		return session{}, "", err
+	}
+	if reused {
+		return session{}, "", errRefreshReused
+	}
+	return s, newRaw, nil
+}
+
+func refresh(db *gorm.DB, km *keys.Manager) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.RefreshReq
+		if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+			return
+		}
+
+		now := time.Now()
+		s, raw, err := rotate(db.WithContext(c.Request.Context()), req.RefreshToken, now)
+		switch {
+		case errors.Is(err, errInvalidRefresh):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid refresh token"})
+			return
+		case errors.Is(err, errRefreshReused):
+			fmt.Printf("[AUTH] ❌ Refresh token reuse detected, all sessions revoked\n")
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "refresh token reuse detected"})
+			return
+		case err != nil:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+
+		respondWithTokens(c, km, s, raw, now)
+	}
+}
+
+// logout opoziva celu familiju kojoj refresh token pripada. Nepoznat token
+// nije greska, da bi logout bio idempotentan.
+func logout(db *gorm.DB) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.RefreshReq
+		if err := c.ShouldBindJSON(&req); err != nil || req.RefreshToken == "" {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "refresh_token is required"})
+			return
+		}
+
+		tx := db.WithContext(c.Request.Context())
+		var rt types.RefreshToken
+		res := tx.Where("token_hash = ?", hashToken(req.RefreshToken)).Limit(1).Find(&rt)
+		if res.Error != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+			return
+		}
+		if res.RowsAffected > 0 {
+			if err := revokeFamily(tx, rt.FamilyID, time.Now()); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "database error"})
+				return
+			}
+		}
+
+		c.Status(http.StatusNoContent)
+	}
+}
+
+// serviceToken izdaje SERVICE token drugim servisima (client credentials).
+func serviceToken(km *keys.Manager, clients map[string]string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req types.ServiceTokenReq
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "bad request"})
+			return
+		}
+
+		secret, ok := clients[req.ClientID]
+		if !ok || subtle.ConstantTimeCompare([]byte(secret), []byte(req.ClientSecret)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid client credentials"})
+			return
+		}
+
+		signed, exp, err := km.ServiceToken(req.ClientID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "signing failed"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"access_token": signed,
+			"expires_in":   int64(time.Until(exp).Seconds()),
+			"token_type":   "Bearer",
+			"role":         jwtauth.RoleService,
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package user
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"auth/types"
+	"common/dbtest"
+
+	"gorm.io/gorm"
+)
+
+// newSession upisuje korisnika i otvara mu novu familiju refresh tokena.
+func newSession(t *testing.T, db *gorm.DB, email string, now time.Time) (*types.User, string) {
+	t.Helper()
+	u := &types.User{Email: email, Password: "x", FirstName: "Pera", LastName: "Peric", Role: types.RoleCitizen, IsActive: true}
+	if err := db.Create(u).Error; err != nil {
+		t.Fatal(err)
+	}
+	raw, _, err := createRefreshToken(db, "", sessionFor(u), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u, raw
+}
+
+func refreshToken(t *testing.T, db *gorm.DB, raw string) types.RefreshToken {
+	t.Helper()
+	var rt types.RefreshToken
+	if err := db.First(&rt, "token_hash = ?", hashToken(raw)).Error; err != nil {
+		t.Fatal(err)
+	}
+	return rt
+}
+
+func TestRotate(t *testing.T) {
+	db := dbtest.Open(t, "", &types.User{}, &types.RefreshToken{})
+	now := time.Now()
+	u, raw := newSession(t, db, "pera@test.rs", now)
+
+	s, next, err := rotate(db, raw, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.UserID != u.ID || s.Email != u.Email || next == "" || next == raw {
+		t.Fatalf("rotate = %+v, %q", s, next)
+	}
+	old, cur := refreshToken(t, db, raw), refreshToken(t, db, next)
+	if old.RevokedAt == nil || old.ReplacedByID != cur.ID {
+		t.Fatalf("old token = %+v, want revoked and replaced by %s", old, cur.ID)
+	}
+	if cur.FamilyID != old.FamilyID || cur.RevokedAt != nil {
+		t.Fatalf("new token = %+v, want active in family %s", cur, old.FamilyID)
+	}
+
+	// nova rotacija sa aktuelnim tokenom radi
+	_, next2, err := rotate(db, next, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, _, err := rotate(db, "nepoznat", now); !errors.Is(err, errInvalidRefresh) {
+		t.Fatalf("unknown token err = %v, want errInvalidRefresh", err)
+	}
+	if _, _, err := rotate(db, next2, now.Add(refreshTokenTTL+time.Second)); !errors.Is(err, errInvalidRefresh) {
+		t.Fatalf("expired token err = %v, want errInvalidRefresh", err)
+	}
+}
+
+func TestRotateReuseRevokesAllSessions(t *testing.T) {
+	db := dbtest.Open(t, "", &types.User{}, &types.RefreshToken{})
+	now := time.Now()
+	u, raw := newSession(t, db, "pera@test.rs", now)
+	// druga sesija istog korisnika (drugi uredjaj)
+	other, _, err := createRefreshToken(db, "", sessionFor(u), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, stranger := newSession(t, db, "mika@test.rs", now)
+
+	_, next, err := rotate(db, raw, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := rotate(db, raw, now); !errors.Is(err, errRefreshReused) {
+		t.Fatalf("reused token err = %v, want errRefreshReused", err)
+	}
+
+	for _, tok := range []string{next, other} {
+		if _, _, err := rotate(db, tok, now); !errors.Is(err, errInvalidRefresh) {
+			t.Errorf("session after reuse err = %v, want errInvalidRefresh", err)
+		}
+	}
+	if _, _, err := rotate(db, stranger, now); err != nil {
+		t.Fatalf("other user's session was revoked: %v", err)
+	}
+}
+
+func TestRotateInactiveUser(t *testing.T) {
+	db := dbtest.Open(t, "", &types.User{}, &types.RefreshToken{})
+	now := time.Now()
+	u, raw := newSession(t, db, "pera@test.rs", now)
+	if err := db.Model(u).Update("is_active", false).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := rotate(db, raw, now); !errors.Is(err, errInvalidRefresh) {
+		t.Fatalf("inactive user err = %v, want errInvalidRefresh", err)
+	}
+	if rt := refreshToken(t, db, raw); rt.RevokedAt != nil {
+		t.Fatal("rejected rotation revoked the token")
+	}
+}
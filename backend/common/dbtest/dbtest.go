@@ -0,0 +1,48 @@
+// Package dbtest otvara SQLite bazu u memoriji za testove store-ova.
+// Upiti specificni za Postgres (FOR UPDATE, FILTER, LATERAL...) se ovde ne
+// mogu proveriti; SQLite drajver zakljucavanje samo preskace.
+package dbtest
+
+import (
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// Open pravi praznu bazu sa tabelama za date modele. prefix je TablePrefix
+// servisa ("" za podrazumevana imena tabela).
+func Open(t testing.TB, prefix string, models ...any) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+		Logger:                                   logger.Discard,
+		NamingStrategy:                           schema.NamingStrategy{TablePrefix: prefix},
+		DisableForeignKeyConstraintWhenMigrating: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// svaka nova konekcija bi otvorila drugu, praznu bazu
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	if err := db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// Insert upisuje zapise bez povezanih modela.
+func Insert(t testing.TB, db *gorm.DB, records ...any) {
+	t.Helper()
+	for _, r := range records {
+		if err := db.Omit(clause.Associations).Create(r).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+}
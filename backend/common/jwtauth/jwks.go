@@ -0,0 +1,171 @@
+package jwtauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// KeySet vraca javni kljuc za dati key ID.
+type KeySet interface {
+	Key(ctx context.Context, kid string) (crypto.PublicKey, error)
+}
+
+// JWK je javni Ed25519 kljuc u JSON Web Key formatu (RFC 8037).
+type JWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	X   string `json:"x"`
+}
+
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+func NewJWK(kid string, pub ed25519.PublicKey) JWK {
+	return JWK{
+		Kty: "OKP",
+		Crv: "Ed25519",
+		Kid: kid,
+		Use: "sig",
+		Alg: "EdDSA",
+		X:   base64.RawURLEncoding.EncodeToString(pub),
+	}
+}
+
+func (k JWK) PublicKey() (ed25519.PublicKey, error) {
+	if k.Kty != "OKP" || k.Crv != "Ed25519" {
+		return nil, fmt.Errorf("unsupported key type %s/%s", k.Kty, k.Crv)
+	}
+	b, err := base64.RawURLEncoding.DecodeString(k.X)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != ed25519.PublicKeySize {
+		return nil, errors.New("invalid Ed25519 key size")
+	}
+	return ed25519.PublicKey(b), nil
+}
+
+// RemoteKeySet preuzima JWKS sa auth servisa i kesira ga. Kljuc sa
+// nepoznatim kid-om izaziva novo preuzimanje (najcesce jednom u MinRefresh),
+// tako da se rotacija kljuca na auth strani vidi odmah. Istovremeni zahtevi
+// cekaju isto preuzimanje, a kes je za to vreme dostupan ostalima.
+type RemoteKeySet struct {
+	URL        string
+	Client     *http.Client
+	TTL        time.Duration
+	MinRefresh time.Duration
+
+	mu         sync.Mutex
+	keys       map[string]ed25519.PublicKey
+	fetchedAt  time.Time
+	triedAt    time.Time
+	fetchErr   error
+	refreshing chan struct{}
+}
+
+func NewRemoteKeySet(url string) *RemoteKeySet {
+	return &RemoteKeySet{
+		URL:        url,
+		Client:     &http.Client{Timeout: 3 * time.Second},
+		TTL:        10 * time.Minute,
+		MinRefresh: 10 * time.Second,
+	}
+}
+
+func (s *RemoteKeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	key, known := s.keys[kid]
+	if known && time.Since(s.fetchedAt) <= s.TTL {
+		s.mu.Unlock()
+		return key, nil
+	}
+	done := s.refreshing
+	if done == nil && time.Since(s.triedAt) >= s.MinRefresh {
+		s.triedAt = time.Now()
+		done = make(chan struct{})
+		s.refreshing = done
+		go s.refresh(done)
+	}
+	s.mu.Unlock()
+
+	if done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			if known {
+				return key, nil
+			}
+			return nil, ctx.Err()
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// ako osvezavanje ne uspe, stari kljuc i dalje vazi
+	if key, known = s.keys[kid]; known {
+		return key, nil
+	}
+	if done != nil && s.fetchErr != nil {
+		return nil, s.fetchErr
+	}
+	return nil, ErrUnknownKey
+}
+
+// refresh preuzima JWKS van brave i zatvara done kad zavrsi. Ne zavisi od
+// konteksta zahteva koji ga je pokrenuo, jer ga cekaju i drugi.
+func (s *RemoteKeySet) refresh(done chan struct{}) {
+	keys, err := s.fetch(context.Background())
+
+	s.mu.Lock()
+	if err == nil {
+		s.keys, s.fetchedAt = keys, time.Now()
+	}
+	s.fetchErr, s.refreshing = err, nil
+	s.mu.Unlock()
+	close(done)
+}
+
+func (s *RemoteKeySet) fetch(ctx context.Context) (map[string]ed25519.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", s.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("jwks: unexpected status %d", res.StatusCode)
+	}
+
+	var set JWKS
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]ed25519.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		pub, err := k.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
@@ -0,0 +1,110 @@
+// Package jwtauth proverava access tokene koje izdaje auth servis i
+// stavlja njihove claim-ove u gin.Context.
+package jwtauth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	RoleCitizen = "CITIZEN"
+	RoleMup     = "MUP"
+	RoleTraffic = "TRAFFIC"
+	// RoleService nose tokeni kojima servisi pozivaju jedan drugog.
+	RoleService = "SERVICE"
+)
+
+const claimsKey = "jwtauth.claims"
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type Claims struct {
+	Role  string `json:"role"`
+	ID    string `json:"id"`
+	Email string `json:"email"`
+	// Veza sa MUP registrom; postoji samo za gradjane ciji je nalog
+	// povezan sa MUP vlasnikom.
+	OwnerID  string `json:"owner_id,omitempty"`
+	JMBG     string `json:"jmbg,omitempty"`
+	DriverID string `json:"driver_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+type Config struct {
+	Issuer string
+	// Keys daje javni kljuc po "kid" zaglavlju tokena.
+	Keys KeySet
+}
+
+// Parse proverava potpis (EdDSA, kljuc po kid-u), issuer i rok vazenja.
+func (cfg Config) Parse(ctx context.Context, raw string) (*Claims, error) {
+	claims := &Claims{}
+	_, err := jwt.ParseWithClaims(raw, claims,
+		func(t *jwt.Token) (any, error) {
+			kid, _ := t.Header["kid"].(string)
+			if kid == "" {
+				return nil, errors.New("missing kid")
+			}
+			return cfg.Keys.Key(ctx, kid)
+		},
+		jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}),
+		jwt.WithIssuer(cfg.Issuer),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, errors.Join(ErrInvalidToken, err)
+	}
+	return claims, nil
+}
+
+// Middleware vraca 401 za zahteve bez validnog Bearer tokena. Rute navedene
+// u public (npr. "/health") propusta bez provere.
+func Middleware(cfg Config, public ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if slices.Contains(public, c.FullPath()) {
+			c.Next()
+			return
+		}
+
+		raw, ok := BearerToken(c.GetHeader("Authorization"))
+		if !ok {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+
+		claims, err := cfg.Parse(c.Request.Context(), raw)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "invalid token"})
+			return
+		}
+
+		c.Set(claimsKey, claims)
+		c.Next()
+	}
+}
+
+// FromContext vraca claim-ove koje je Middleware upisao.
+func FromContext(c *gin.Context) (*Claims, bool) {
+	v, ok := c.Get(claimsKey)
+	if !ok {
+		return nil, false
+	}
+	claims, ok := v.(*Claims)
+	return claims, ok
+}
+
+func BearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(strings.TrimSpace(header), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
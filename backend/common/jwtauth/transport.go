@@ -0,0 +1,111 @@
+package jwtauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// TokenSource daje servisni token i trenutak njegovog isteka.
+type TokenSource interface {
+	Token(ctx context.Context) (string, time.Time, error)
+}
+
+// ServiceTransport dodaje servisni Bearer token na svaki odlazni zahtev.
+// Token se kesira i obnavlja malo pre isteka.
+type ServiceTransport struct {
+	Source TokenSource
+	Base   http.RoundTripper
+
+	mu    sync.Mutex
+	token string
+	exp   time.Time
+}
+
+func NewServiceTransport(src TokenSource) *ServiceTransport {
+	return &ServiceTransport{Source: src}
+}
+
+func (t *ServiceTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.currentToken(req.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	out := req.Clone(req.Context())
+	out.Header.Set("Authorization", "Bearer "+token)
+
+	base := t.Base
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return base.RoundTrip(out)
+}
+
+func (t *ServiceTransport) currentToken(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.token != "" && time.Until(t.exp) > 30*time.Second {
+		return t.token, nil
+	}
+
+	token, exp, err := t.Source.Token(ctx)
+	if err != nil {
+		return "", err
+	}
+	t.token, t.exp = token, exp
+	return token, nil
+}
+
+// ClientCredentials trazi servisni token od auth servisa (POST /token).
+// Servisi vise nemaju kljuc za potpisivanje, pa ne mogu sami da ga izdaju.
+type ClientCredentials struct {
+	URL          string
+	ClientID     string
+	ClientSecret string
+	Client       *http.Client
+}
+
+type clientCredentialsReq struct {
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+}
+
+type clientCredentialsResp struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+func (cc ClientCredentials) Token(ctx context.Context) (string, time.Time, error) {
+	body, _ := json.Marshal(clientCredentialsReq{ClientID: cc.ClientID, ClientSecret: cc.ClientSecret})
+	req, err := http.NewRequestWithContext(ctx, "POST", cc.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := cc.Client
+	if client == nil {
+		client = &http.Client{Timeout: 3 * time.Second}
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("service token: unexpected status %d", res.StatusCode)
+	}
+
+	var out clientCredentialsResp
+	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
+		return "", time.Time{}, err
+	}
+	return out.AccessToken, time.Now().Add(time.Duration(out.ExpiresIn) * time.Second), nil
+}
@@ -0,0 +1,239 @@
+// Package migrate izvrsava verzionisane SQL migracije i belezi primenjene
+// verzije u tabeli schema_migrations (po servisu, jer svi dele istu bazu).
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// lockID serijalizuje migracije svih servisa; users tabelu dele auth i
+// traffic-police pa ne smeju da je menjaju istovremeno.
+const lockID = 84_250_007
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt *time.Time
+}
+
+type Runner struct {
+	DB         *gorm.DB
+	Service    string
+	Migrations []Migration
+}
+
+type appliedRow struct {
+	Version   int
+	AppliedAt time.Time
+}
+
+func New(db *gorm.DB, service string, migrations []Migration) Runner {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return Runner{DB: db, Service: service, Migrations: sorted}
+}
+
+func (r Runner) validate() error {
+	seen := map[int]bool{}
+	for _, m := range r.Migrations {
+		if m.Version <= 0 {
+			return fmt.Errorf("migrate: invalid version %d", m.Version)
+		}
+		if seen[m.Version] {
+			return fmt.Errorf("migrate: duplicate version %d", m.Version)
+		}
+		seen[m.Version] = true
+	}
+	return nil
+}
+
+func (r Runner) ensureTable(tx *gorm.DB) error {
+	return tx.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+		service    text        NOT NULL,
+		version    bigint      NOT NULL,
+		name       text        NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now(),
+		PRIMARY KEY (service, version)
+	)`).Error
+}
+
+func (r Runner) applied(tx *gorm.DB) (map[int]time.Time, error) {
+	var rows []appliedRow
+	err := tx.Raw("SELECT version, applied_at FROM schema_migrations WHERE service = ?", r.Service).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[int]time.Time, len(rows))
+	for _, row := range rows {
+		out[row.Version] = row.AppliedAt
+	}
+	return out, nil
+}
+
+// locked izvrsava fn u transakciji koja drzi globalni migracioni lock.
+func (r Runner) locked(ctx context.Context, fn func(tx *gorm.DB) error) error {
+	return r.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", lockID).Error; err != nil {
+			return err
+		}
+		if err := r.ensureTable(tx); err != nil {
+			return err
+		}
+		return fn(tx)
+	})
+}
+
+// Up primenjuje sve neprimenjene migracije redom, svaku u svojoj transakciji.
+func (r Runner) Up(ctx context.Context) ([]Migration, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, m := range r.Migrations {
+		ran := false
+		err := r.locked(ctx, func(tx *gorm.DB) error {
+			applied, err := r.applied(tx)
+			if err != nil {
+				return err
+			}
+			if _, ok := applied[m.Version]; ok {
+				return nil
+			}
+			if err := tx.Exec(m.Up).Error; err != nil {
+				return fmt.Errorf("migrate: %d_%s up: %w", m.Version, m.Name, err)
+			}
+			ran = true
+			return tx.Exec("INSERT INTO schema_migrations (service, version, name) VALUES (?, ?, ?)",
+				r.Service, m.Version, m.Name).Error
+		})
+		if err != nil {
+			return done, err
+		}
+		if ran {
+			done = append(done, m)
+		}
+	}
+	return done, nil
+}
+
+// Down ponistava poslednjih steps primenjenih migracija.
+func (r Runner) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := 0; i < steps; i++ {
+		var reverted *Migration
+		err := r.locked(ctx, func(tx *gorm.DB) error {
+			applied, err := r.applied(tx)
+			if err != nil {
+				return err
+			}
+			for j := len(r.Migrations) - 1; j >= 0; j-- {
+				m := r.Migrations[j]
+				if _, ok := applied[m.Version]; !ok {
+					continue
+				}
+				if err := tx.Exec(m.Down).Error; err != nil {
+					return fmt.Errorf("migrate: %d_%s down: %w", m.Version, m.Name, err)
+				}
+				reverted = &m
+				return tx.Exec("DELETE FROM schema_migrations WHERE service = ? AND version = ?",
+					r.Service, m.Version).Error
+			}
+			return nil
+		})
+		if err != nil {
+			return done, err
+		}
+		if reverted == nil {
+			break
+		}
+		done = append(done, *reverted)
+	}
+	return done, nil
+}
+
+func (r Runner) Status(ctx context.Context) ([]Status, error) {
+	var out []Status
+	err := r.locked(ctx, func(tx *gorm.DB) error {
+		applied, err := r.applied(tx)
+		if err != nil {
+			return err
+		}
+		for _, m := range r.Migrations {
+			st := Status{Version: m.Version, Name: m.Name}
+			if at, ok := applied[m.Version]; ok {
+				st.Applied = true
+				st.AppliedAt = &at
+			}
+			out = append(out, st)
+		}
+		return nil
+	})
+	return out, err
+}
+
+// Command obradjuje podkomandu "migrate": up | down [n] | status.
+func (r Runner) Command(ctx context.Context, args []string, w io.Writer) error {
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	switch cmd {
+	case "up":
+		done, err := r.Up(ctx)
+		for _, m := range done {
+			fmt.Fprintf(w, "up   %04d_%s\n", m.Version, m.Name)
+		}
+		if err == nil && len(done) == 0 {
+			fmt.Fprintln(w, "no pending migrations")
+		}
+		return err
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("migrate: invalid step count %q", args[1])
+			}
+			steps = n
+		}
+		done, err := r.Down(ctx, steps)
+		for _, m := range done {
+			fmt.Fprintf(w, "down %04d_%s\n", m.Version, m.Name)
+		}
+		return err
+	case "status":
+		list, err := r.Status(ctx)
+		for _, st := range list {
+			state := "pending"
+			if st.Applied {
+				state = "applied " + st.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%04d_%s\t%s\n", st.Version, st.Name, state)
+		}
+		return err
+	default:
+		return fmt.Errorf("migrate: unknown command %q (use up, down [n] or status)", cmd)
+	}
+}
@@ -0,0 +1,115 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func TestNewSortsByVersion(t *testing.T) {
+	r := New(nil, "svc", []Migration{{Version: 3}, {Version: 1}, {Version: 2}})
+	for i, m := range r.Migrations {
+		if m.Version != i+1 {
+			t.Fatalf("migrations not sorted: %+v", r.Migrations)
+		}
+	}
+}
+
+// Neispravan spisak se odbija pre bilo kakvog pristupa bazi (DB je nil).
+func TestInvalidMigrationsAreRejected(t *testing.T) {
+	tests := map[string][]Migration{
+		"zero version":      {{Version: 0, Name: "zero"}},
+		"negative version":  {{Version: -1, Name: "negative"}},
+		"duplicate version": {{Version: 1, Name: "a"}, {Version: 1, Name: "b"}},
+	}
+	for name, list := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := New(nil, "svc", list)
+			if _, err := r.Up(context.Background()); err == nil {
+				t.Error("Up accepted an invalid migration list")
+			}
+			if _, err := r.Down(context.Background(), 1); err == nil {
+				t.Error("Down accepted an invalid migration list")
+			}
+		})
+	}
+}
+
+func TestCommandRejectsBadArguments(t *testing.T) {
+	r := New(nil, "svc", nil)
+	for _, args := range [][]string{{"sideways"}, {"down", "x"}, {"down", "0"}} {
+		if err := r.Command(context.Background(), args, &bytes.Buffer{}); err == nil {
+			t.Errorf("Command(%q) = nil, want an error", args)
+		}
+	}
+}
+
+// TestRunnerPostgres pokrece migracije nad pravom bazom; preskace se ako
+// MIGRATE_TEST_DSN nije postavljen.
+func TestRunnerPostgres(t *testing.T) {
+	dsn := os.Getenv("MIGRATE_TEST_DSN")
+	if dsn == "" {
+		t.Skip("MIGRATE_TEST_DSN not set")
+	}
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	service := fmt.Sprintf("migrate_test_%d", time.Now().UnixNano())
+	table := service + "_things"
+	t.Cleanup(func() {
+		db.Exec("DROP TABLE IF EXISTS " + table)
+		db.Exec("DELETE FROM schema_migrations WHERE service = ?", service)
+	})
+
+	list := []Migration{
+		{Version: 2, Name: "add_note", Up: "ALTER TABLE " + table + " ADD COLUMN note text", Down: "ALTER TABLE " + table + " DROP COLUMN note"},
+		{Version: 1, Name: "create_things", Up: "CREATE TABLE " + table + " (id int)", Down: "DROP TABLE " + table},
+	}
+	r := New(db, service, list)
+	ctx := context.Background()
+
+	done, err := r.Up(ctx)
+	if err != nil || len(done) != 2 || done[0].Version != 1 {
+		t.Fatalf("Up = %+v, %v", done, err)
+	}
+	if done, err = r.Up(ctx); err != nil || len(done) != 0 {
+		t.Fatalf("second Up = %+v, %v; want nothing to do", done, err)
+	}
+
+	done, err = r.Down(ctx, 1)
+	if err != nil || len(done) != 1 || done[0].Version != 2 {
+		t.Fatalf("Down = %+v, %v", done, err)
+	}
+
+	var out bytes.Buffer
+	if err := r.Command(ctx, []string{"status"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "0001_create_things\tapplied") ||
+		!strings.Contains(out.String(), "0002_add_note\tpending") {
+		t.Fatalf("status output:\n%s", out.String())
+	}
+
+	// neuspela migracija se ne belezi kao primenjena
+	r.Migrations = append(r.Migrations, Migration{Version: 3, Name: "broken", Up: "ALTER TABLE missing_table ADD COLUMN x int"})
+	if _, err := r.Up(ctx); err == nil {
+		t.Fatal("Up ignored a failing migration")
+	}
+	st, err := r.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st) != 3 || !st[1].Applied || st[2].Applied {
+		t.Fatalf("status after failure = %+v", st)
+	}
+}
@@ -0,0 +1,99 @@
+package mupclient
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen: MUP je nedavno uzastopno padao, zahtev se ni ne salje.
+var ErrCircuitOpen = errors.New("mup: circuit breaker open")
+
+type BreakerState string
+
+const (
+	BreakerClosed   BreakerState = "CLOSED"
+	BreakerOpen     BreakerState = "OPEN"
+	BreakerHalfOpen BreakerState = "HALF_OPEN"
+)
+
+// Breaker se otvara posle Threshold uzastopnih neuspeha i tada odbija
+// zahteve tokom Cooldown. Posle toga pusta jedan probni zahtev (HALF_OPEN):
+// uspeh ga zatvara, neuspeh ponovo otvara.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    BreakerState
+	failures int
+	openedAt time.Time
+	probing  bool
+	opens    int64
+}
+
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: threshold, Cooldown: cooldown, state: BreakerClosed}
+}
+
+// allow kaze da li zahtev sme da se posalje.
+func (b *Breaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case BreakerOpen:
+		if now.Sub(b.openedAt) < b.Cooldown {
+			return false
+		}
+		b.state, b.probing = BreakerHalfOpen, true
+		return true
+	case BreakerHalfOpen:
+		if b.probing {
+			return false
+		}
+		b.probing = true
+		return true
+	}
+	return true
+}
+
+func (b *Breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.state, b.failures, b.probing = BreakerClosed, 0, false
+}
+
+func (b *Breaker) failure(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.state == BreakerHalfOpen || b.failures >= b.Threshold {
+		if b.state != BreakerOpen {
+			b.opens++
+		}
+		b.state, b.openedAt, b.probing = BreakerOpen, now, false
+	}
+}
+
+// release oslobadja probni zahtev ciji ishod nista ne govori o MUP-u (npr.
+// pozivalac je otkazao ctx).
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (b *Breaker) State() BreakerState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// Opens je koliko puta se breaker otvorio.
+func (b *Breaker) Opens() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.opens
+}
@@ -0,0 +1,99 @@
+package mupclient
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBreakerOpensAfterThreshold(t *testing.T) {
+	b := NewBreaker(3, time.Minute)
+	now := time.Now()
+
+	b.failure(now)
+	b.failure(now)
+	if b.State() != BreakerClosed || !b.allow(now) {
+		t.Fatalf("breaker opened before threshold: %s", b.State())
+	}
+
+	b.failure(now)
+	if b.State() != BreakerOpen {
+		t.Fatalf("state = %s, want OPEN", b.State())
+	}
+	if b.allow(now.Add(time.Second)) {
+		t.Fatal("open breaker allowed a request during cooldown")
+	}
+	if b.Opens() != 1 {
+		t.Fatalf("opens = %d, want 1", b.Opens())
+	}
+}
+
+func TestBreakerSuccessResetsFailures(t *testing.T) {
+	b := NewBreaker(3, time.Minute)
+	now := time.Now()
+
+	b.failure(now)
+	b.failure(now)
+	b.success()
+	b.failure(now)
+	b.failure(now)
+	if b.State() != BreakerClosed {
+		t.Fatalf("state = %s, want CLOSED (failures are not consecutive)", b.State())
+	}
+}
+
+func TestBreakerHalfOpenAllowsOneProbe(t *testing.T) {
+	b := NewBreaker(1, time.Minute)
+	now := time.Now()
+	b.failure(now)
+
+	later := now.Add(time.Minute)
+	if !b.allow(later) {
+		t.Fatal("breaker did not allow a probe after cooldown")
+	}
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("state = %s, want HALF_OPEN", b.State())
+	}
+	if b.allow(later) {
+		t.Fatal("breaker allowed a second request while probing")
+	}
+
+	b.success()
+	if b.State() != BreakerClosed || !b.allow(later) {
+		t.Fatalf("successful probe did not close the breaker: %s", b.State())
+	}
+}
+
+func TestBreakerFailedProbeReopens(t *testing.T) {
+	b := NewBreaker(1, time.Minute)
+	now := time.Now()
+	b.failure(now)
+
+	later := now.Add(time.Minute)
+	b.allow(later)
+	b.failure(later)
+	if b.State() != BreakerOpen {
+		t.Fatalf("state = %s, want OPEN", b.State())
+	}
+	if b.allow(later.Add(time.Second)) {
+		t.Fatal("cooldown did not restart after a failed probe")
+	}
+	if b.Opens() != 2 {
+		t.Fatalf("opens = %d, want 2", b.Opens())
+	}
+}
+
+func TestBreakerReleaseFreesProbe(t *testing.T) {
+	b := NewBreaker(1, time.Minute)
+	now := time.Now()
+	b.failure(now)
+
+	later := now.Add(time.Minute)
+	b.allow(later)
+	b.release()
+	if b.State() != BreakerHalfOpen {
+		t.Fatalf("state = %s, want HALF_OPEN", b.State())
+	}
+	if !b.allow(later) {
+		t.Fatal("released probe was not handed to the next request")
+	}
+}
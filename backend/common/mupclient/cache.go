@@ -0,0 +1,82 @@
+package mupclient
+
+import (
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Cache cuva uspesne odgovore na GET zahteve (vozila, vozaci) TTL vremena.
+// Kad se popuni MaxEntries, prvo se izbacuju istekli, pa ako ne pomogne ceo
+// kes se prazni.
+type Cache struct {
+	TTL        time.Duration
+	MaxEntries int
+
+	mu    sync.Mutex
+	items map[string]cacheItem
+
+	hits   atomic.Int64
+	misses atomic.Int64
+}
+
+type cacheItem struct {
+	value   any
+	expires time.Time
+}
+
+func NewCache(ttl time.Duration, maxEntries int) *Cache {
+	return &Cache{TTL: ttl, MaxEntries: maxEntries, items: map[string]cacheItem{}}
+}
+
+func (c *Cache) get(key string, now time.Time) (any, bool) {
+	c.mu.Lock()
+	it, ok := c.items[key]
+	if ok && !now.Before(it.expires) {
+		delete(c.items, key)
+		ok = false
+	}
+	c.mu.Unlock()
+
+	if ok {
+		c.hits.Add(1)
+		return it.value, true
+	}
+	c.misses.Add(1)
+	return nil, false
+}
+
+func (c *Cache) set(key string, value any, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.MaxEntries > 0 && len(c.items) >= c.MaxEntries {
+		for k, it := range c.items {
+			if !now.Before(it.expires) {
+				delete(c.items, k)
+			}
+		}
+		if len(c.items) >= c.MaxEntries {
+			clear(c.items)
+		}
+	}
+	c.items[key] = cacheItem{value: value, expires: now.Add(c.TTL)}
+}
+
+// invalidate brise kljuc i sve kljuceve ispod njega (key + "/...").
+func (c *Cache) invalidate(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for k := range c.items {
+		if k == key || strings.HasPrefix(k, key+"/") {
+			delete(c.items, k)
+		}
+	}
+}
+
+func (c *Cache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.items)
+}
@@ -0,0 +1,109 @@
+package mupclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCacheExpires(t *testing.T) {
+	c := NewCache(time.Minute, 10)
+	now := time.Now()
+	c.set("/drivers/1", 1, now)
+
+	if v, ok := c.get("/drivers/1", now.Add(59*time.Second)); !ok || v != 1 {
+		t.Fatalf("get before TTL = %v, %v", v, ok)
+	}
+	if _, ok := c.get("/drivers/1", now.Add(time.Minute)); ok {
+		t.Fatal("entry survived its TTL")
+	}
+	if c.len() != 0 {
+		t.Fatalf("expired entry was not removed, len = %d", c.len())
+	}
+	if c.hits.Load() != 1 || c.misses.Load() != 1 {
+		t.Fatalf("hits/misses = %d/%d, want 1/1", c.hits.Load(), c.misses.Load())
+	}
+}
+
+func TestCacheEvictsExpiredFirst(t *testing.T) {
+	c := NewCache(time.Minute, 2)
+	now := time.Now()
+	c.set("old", 1, now.Add(-2*time.Minute))
+	c.set("fresh", 2, now)
+
+	c.set("new", 3, now)
+	if _, ok := c.get("fresh", now); !ok {
+		t.Fatal("live entry was evicted while an expired one was available")
+	}
+	if _, ok := c.get("old", now); ok {
+		t.Fatal("expired entry was kept")
+	}
+
+	// nema isteklih: kes se prazni
+	c.set("newest", 4, now)
+	if c.len() != 1 {
+		t.Fatalf("len = %d, want 1 after a full cache was cleared", c.len())
+	}
+}
+
+func TestCacheInvalidateSubtree(t *testing.T) {
+	c := NewCache(time.Minute, 10)
+	now := time.Now()
+	for _, k := range []string{"/drivers/1", "/drivers/1/suspensions", "/drivers/10"} {
+		c.set(k, k, now)
+	}
+
+	c.invalidate("/drivers/1")
+	for k, want := range map[string]bool{
+		"/drivers/1":             false,
+		"/drivers/1/suspensions": false,
+		"/drivers/10":            true,
+	} {
+		if _, ok := c.get(k, now); ok != want {
+			t.Errorf("%s cached = %v, want %v", k, ok, want)
+		}
+	}
+}
+
+func TestClientCachesAndInvalidatesDriver(t *testing.T) {
+	var gets atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+		json.NewEncoder(w).Encode(Driver{ID: "D1", NumberOfViolationPoints: 3})
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, srv.Client())
+	ctx := context.Background()
+
+	d, err := c.GetDriver(ctx, "D1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.NumberOfViolationPoints = 99
+	if d, err = c.GetDriver(ctx, "D1"); err != nil {
+		t.Fatal(err)
+	}
+	if gets.Load() != 1 {
+		t.Fatalf("GET requests = %d, want 1 (second read from cache)", gets.Load())
+	}
+	if d.NumberOfViolationPoints != 3 {
+		t.Fatalf("caller's change leaked into the cache: %d points", d.NumberOfViolationPoints)
+	}
+
+	if _, err := c.AddPoints(ctx, "D1", PointsRequest{Delta: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.GetDriver(ctx, "D1"); err != nil {
+		t.Fatal(err)
+	}
+	if gets.Load() != 2 {
+		t.Fatalf("GET requests = %d, want 2 (AddPoints invalidates the driver)", gets.Load())
+	}
+}
@@ -0,0 +1,354 @@
+// Package mupclient je klijent za REST API mup-vehicles servisa, zajednicki
+// za auth i traffic-police.
+package mupclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// maxErrorBody je koliko tela odgovora van 2xx se cita za poruku greske.
+const maxErrorBody = 4 << 10
+
+// RetryPolicy vazi samo za GET zahteve. Pauza pre i-tog ponavljanja je
+// slucajna izmedju 0 i min(MaxDelay, BaseDelay*2^i) (full jitter).
+type RetryPolicy struct {
+	Attempts  int
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// Podrazumevana otpornost klijenta koji vraca New.
+var (
+	DefaultRetry            = RetryPolicy{Attempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: time.Second}
+	DefaultBreakerThreshold = 5
+	DefaultBreakerCooldown  = 10 * time.Second
+	DefaultCacheTTL         = 15 * time.Second
+	DefaultCacheEntries     = 1000
+)
+
+func (p RetryPolicy) delay(retry int) time.Duration {
+	d := p.BaseDelay
+	for i := 1; i < retry && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	d = min(d, p.MaxDelay)
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d + 1)
+}
+
+// Client salje zahteve MUP-u. HTTP klijent odredjuje timeout po pokusaju i
+// autentikaciju (obicno jwtauth.ServiceTransport). Breaker i Cache mogu biti
+// nil; tada se ne koriste.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+	Retry   RetryPolicy
+	Breaker *Breaker
+	Cache   *Cache
+
+	requests       atomic.Int64
+	failures       atomic.Int64
+	retries        atomic.Int64
+	shortCircuited atomic.Int64
+}
+
+// New pravi klijent sa podrazumevanim ponavljanjem, breakerom i kesom.
+func New(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		HTTP:    httpClient,
+		Retry:   DefaultRetry,
+		Breaker: NewBreaker(DefaultBreakerThreshold, DefaultBreakerCooldown),
+		Cache:   NewCache(DefaultCacheTTL, DefaultCacheEntries),
+	}
+}
+
+// Stats su metrike klijenta: HTTP pokusaji, neuspesi (transport i 5xx),
+// ponavljanja, zahtevi odbijeni otvorenim breakerom i pogoci kesa.
+type Stats struct {
+	BreakerState   BreakerState `json:"breakerState"`
+	BreakerOpens   int64        `json:"breakerOpens"`
+	Requests       int64        `json:"requests"`
+	Failures       int64        `json:"failures"`
+	Retries        int64        `json:"retries"`
+	ShortCircuited int64        `json:"shortCircuited"`
+	CacheHits      int64        `json:"cacheHits"`
+	CacheMisses    int64        `json:"cacheMisses"`
+	CacheHitRate   float64      `json:"cacheHitRate"`
+	CacheEntries   int          `json:"cacheEntries"`
+}
+
+func (c *Client) Stats() Stats {
+	st := Stats{
+		BreakerState:   BreakerClosed,
+		Requests:       c.requests.Load(),
+		Failures:       c.failures.Load(),
+		Retries:        c.retries.Load(),
+		ShortCircuited: c.shortCircuited.Load(),
+	}
+	if c.Breaker != nil {
+		st.BreakerState, st.BreakerOpens = c.Breaker.State(), c.Breaker.Opens()
+	}
+	if c.Cache != nil {
+		st.CacheHits, st.CacheMisses = c.Cache.hits.Load(), c.Cache.misses.Load()
+		st.CacheEntries = c.Cache.len()
+		if total := st.CacheHits + st.CacheMisses; total > 0 {
+			st.CacheHitRate = float64(st.CacheHits) / float64(total)
+		}
+	}
+	return st
+}
+
+func (c *Client) GetVehicle(ctx context.Context, registration string) (*Vehicle, error) {
+	return cached[Vehicle](ctx, c, "/vehicles/"+url.PathEscape(registration))
+}
+
+// GetVehicleByOwnerJMBG vraca (jedno) vozilo vlasnika.
+func (c *Client) GetVehicleByOwnerJMBG(ctx context.Context, jmbg string) (*Vehicle, error) {
+	return cached[Vehicle](ctx, c, "/vehicles/owner/"+url.PathEscape(jmbg))
+}
+
+func (c *Client) GetDriver(ctx context.Context, id string) (*Driver, error) {
+	return cached[Driver](ctx, c, "/drivers/"+url.PathEscape(id))
+}
+
+// GetOwnerByEmail vraca vlasnika sa datim email-om; rezultat se ne kesira.
+func (c *Client) GetOwnerByEmail(ctx context.Context, email string) (*Owner, error) {
+	return call[Owner](ctx, c, http.MethodGet, "/owners/email/"+url.PathEscape(email), nil, nil)
+}
+
+// AddPoints menja poene vozaca i vraca vozaca posle promene.
+func (c *Client) AddPoints(ctx context.Context, driverID string, req PointsRequest) (*Driver, error) {
+	var hdr http.Header
+	if req.IdempotencyKey != "" {
+		hdr = http.Header{"Idempotency-Key": {req.IdempotencyKey}}
+	}
+	path := "/drivers/" + url.PathEscape(driverID)
+	defer c.invalidate(path)
+	return call[Driver](ctx, c, http.MethodPatch, path+"/points", hdr, req)
+}
+
+func (c *Client) PointsHistory(ctx context.Context, driverID string) ([]PointsEntry, error) {
+	out, err := call[[]PointsEntry](ctx, c, http.MethodGet, "/drivers/"+url.PathEscape(driverID)+"/points/history", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return *out, nil
+}
+
+func (c *Client) Suspend(ctx context.Context, driverID string, req SuspendRequest) (*Driver, error) {
+	path := "/drivers/" + url.PathEscape(driverID)
+	defer c.invalidate(path)
+	return call[Driver](ctx, c, http.MethodPatch, path+"/suspend", nil, req)
+}
+
+func (c *Client) ListSuspensions(ctx context.Context, driverID string) ([]Suspension, error) {
+	out, err := call[[]Suspension](ctx, c, http.MethodGet, "/drivers/"+url.PathEscape(driverID)+"/suspensions", nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	return *out, nil
+}
+
+// RegistryChanges vraca stranicu vlasnika, vozila i vozaca izmenjenih od
+// since (ceo registar kad je since nula); page je Next prethodne stranice.
+// Rezultat se ne kesira.
+func (c *Client) RegistryChanges(ctx context.Context, since time.Time, page string) (*RegistryChanges, error) {
+	q := url.Values{}
+	switch {
+	case page != "":
+		q.Set("page", page)
+	case !since.IsZero():
+		q.Set("since", since.UTC().Format(time.RFC3339Nano))
+	}
+	path := "/registry/changes"
+	if len(q) > 0 {
+		path += "?" + q.Encode()
+	}
+	return call[RegistryChanges](ctx, c, http.MethodGet, path, nil, nil)
+}
+
+// Login proverava kredencijale vlasnika ili administratora. Pogresni
+// kredencijali daju gresku za koju vazi errors.Is(err, ErrUnauthorized).
+func (c *Client) Login(ctx context.Context, email, password string) (*Identity, error) {
+	body := struct {
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}{email, password}
+	return call[Identity](ctx, c, http.MethodPost, "/login", nil, body)
+}
+
+// call je do sa tipiziranim rezultatom; za gresku vraca nil.
+func call[T any](ctx context.Context, c *Client, method, path string, hdr http.Header, body any) (*T, error) {
+	var out T
+	if err := c.do(ctx, method, path, hdr, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// cached je GET kroz kes. Pozivalac dobija kopiju, pa izmena rezultata ne
+// menja kesiranu vrednost.
+func cached[T any](ctx context.Context, c *Client, path string) (*T, error) {
+	if c.Cache != nil {
+		if v, ok := c.Cache.get(path, time.Now()); ok {
+			out := v.(T)
+			return &out, nil
+		}
+	}
+	out, err := call[T](ctx, c, http.MethodGet, path, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.Cache != nil {
+		c.Cache.set(path, *out, time.Now())
+	}
+	return out, nil
+}
+
+func (c *Client) invalidate(path string) {
+	if c.Cache != nil {
+		c.Cache.invalidate(path)
+	}
+}
+
+// do salje zahtev i dekodira 2xx odgovor u out, ponavljajuci GET prema
+// Retry. Greske su *StatusError, *TransportError ili *DecodeError.
+func (c *Client) do(ctx context.Context, method, path string, hdr http.Header, body, out any) error {
+	var payload []byte
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = b
+	}
+
+	attempts := 1
+	if method == http.MethodGet && c.Retry.Attempts > 1 {
+		attempts = c.Retry.Attempts
+	}
+
+	var err error
+	for i := 0; i < attempts; i++ {
+		if i > 0 {
+			c.retries.Add(1)
+			t := time.NewTimer(c.Retry.delay(i))
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return err
+			case <-t.C:
+			}
+		}
+		err = c.once(ctx, method, path, hdr, payload, out)
+		if !retryable(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// retryable: ponavljaju se greske transporta (i timeout pokusaja) i 5xx/429,
+// ali ne i zahtevi koje je odbio breaker.
+func retryable(err error) bool {
+	if err == nil || errors.Is(err, ErrCircuitOpen) {
+		return false
+	}
+	var te *TransportError
+	if errors.As(err, &te) {
+		return true
+	}
+	var se *StatusError
+	return errors.As(err, &se) && (se.StatusCode >= 500 || se.StatusCode == http.StatusTooManyRequests)
+}
+
+// once je jedan HTTP pokusaj kroz breaker.
+func (c *Client) once(ctx context.Context, method, path string, hdr http.Header, payload []byte, out any) error {
+	if c.Breaker != nil && !c.Breaker.allow(time.Now()) {
+		c.shortCircuited.Add(1)
+		return &TransportError{Method: method, Path: path, Err: ErrCircuitOpen}
+	}
+
+	err := c.send(ctx, method, path, hdr, payload, out)
+
+	var (
+		te *TransportError
+		se *StatusError
+	)
+	failed := errors.As(err, &te) || (errors.As(err, &se) && se.StatusCode >= 500)
+	if failed {
+		c.failures.Add(1)
+	}
+	if c.Breaker != nil {
+		switch {
+		case failed && ctx.Err() != nil:
+			c.Breaker.release() // pozivalac je odustao, MUP nije kriv
+		case failed:
+			c.Breaker.failure(time.Now())
+		default:
+			c.Breaker.success()
+		}
+	}
+	return err
+}
+
+func (c *Client) send(ctx context.Context, method, path string, hdr http.Header, payload []byte, out any) error {
+	var rd io.Reader
+	if payload != nil {
+		rd = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, rd)
+	if err != nil {
+		return err
+	}
+	for k, v := range hdr {
+		req.Header[k] = v
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.Header.Set("Accept", "application/json")
+
+	c.requests.Add(1)
+	res, err := c.HTTP.Do(req)
+	if err != nil {
+		return &TransportError{Method: method, Path: path, Err: err}
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &StatusError{Method: method, Path: path, StatusCode: res.StatusCode, Message: errorMessage(res.Body)}
+	}
+	if err := json.NewDecoder(res.Body).Decode(out); err != nil {
+		return &DecodeError{Method: method, Path: path, Err: err}
+	}
+	return nil
+}
+
+// errorMessage vadi "error" iz JSON tela greske; inace vraca skraceno telo.
+func errorMessage(r io.Reader) string {
+	b, _ := io.ReadAll(io.LimitReader(r, maxErrorBody))
+	var e struct {
+		Error string `json:"error"`
+	}
+	if json.Unmarshal(b, &e) == nil && e.Error != "" {
+		return e.Error
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,122 @@
+package mupclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientAddPointsSendsTypedRequest(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPatch || r.URL.Path != "/drivers/D1/points" {
+			t.Errorf("request = %s %s", r.Method, r.URL.Path)
+		}
+		if got := r.Header.Get("Idempotency-Key"); got != "violation:V1" {
+			t.Errorf("Idempotency-Key = %q", got)
+		}
+		var req PointsRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Delta != 3 || req.ViolationID != "V1" {
+			t.Errorf("body = %+v, %v", req, err)
+		}
+		w.Write([]byte(`{"id":"D1","numberOfViolationPoints":3,"owner":{"id":"O1"}}`))
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, srv.Client())
+	d, err := c.AddPoints(context.Background(), "D1", PointsRequest{Delta: 3, ViolationID: "V1", IdempotencyKey: "violation:V1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.NumberOfViolationPoints != 3 || d.Owner.ID != "O1" {
+		t.Fatalf("driver = %+v", d)
+	}
+}
+
+func TestClientMapsStatusErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/drivers/D404":
+			http.Error(w, `{"error":"driver not found"}`, http.StatusNotFound)
+		case "/login":
+			http.Error(w, `{"error":"bad credentials"}`, http.StatusUnauthorized)
+		default:
+			w.Write([]byte(`not json`))
+		}
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, srv.Client())
+	ctx := context.Background()
+
+	_, err := c.GetDriver(ctx, "D404")
+	var se *StatusError
+	if !errors.Is(err, ErrNotFound) || !errors.As(err, &se) || se.Message != "driver not found" {
+		t.Fatalf("404 err = %v, want ErrNotFound with the server message", err)
+	}
+	if _, err := c.Login(ctx, "a@b.c", "x"); !errors.Is(err, ErrUnauthorized) {
+		t.Fatalf("401 err = %v, want ErrUnauthorized", err)
+	}
+	var de *DecodeError
+	if _, err := c.GetVehicle(ctx, "BG-001-AA"); !errors.As(err, &de) {
+		t.Fatalf("bad body err = %v, want DecodeError", err)
+	}
+}
+
+func TestClientRetriesGetThenOpensBreaker(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, `{"error":"down"}`, http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, srv.Client())
+	c.Retry = RetryPolicy{Attempts: 3}
+	c.Breaker = NewBreaker(3, time.Minute)
+	c.Cache = nil
+
+	_, err := c.GetDriver(context.Background(), "D1")
+	if !Unavailable(err) {
+		t.Fatalf("err = %v, want an unavailable error", err)
+	}
+	if requests.Load() != 3 {
+		t.Fatalf("requests = %d, want 3 attempts", requests.Load())
+	}
+
+	_, err = c.GetDriver(context.Background(), "D1")
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("err = %v, want ErrCircuitOpen", err)
+	}
+	if requests.Load() != 3 {
+		t.Fatalf("open breaker let a request through (%d requests)", requests.Load())
+	}
+	if st := c.Stats(); st.ShortCircuited != 1 || st.BreakerOpens != 1 {
+		t.Fatalf("stats = %+v", st)
+	}
+}
+
+func TestClientDoesNotRetryNotFound(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		http.Error(w, `{"error":"driver not found"}`, http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	c := New(srv.URL, srv.Client())
+	_, err := c.GetDriver(context.Background(), "D1")
+	if !errors.Is(err, ErrNotFound) || Unavailable(err) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	if requests.Load() != 1 {
+		t.Fatalf("requests = %d, want 1", requests.Load())
+	}
+	if c.Breaker.State() != BreakerClosed {
+		t.Fatalf("404 changed the breaker to %s", c.Breaker.State())
+	}
+}
@@ -0,0 +1,73 @@
+package mupclient
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNotFound: MUP je odgovorio 404 (nema vozila, vozaca...).
+	ErrNotFound = errors.New("mup: not found")
+	// ErrUnauthorized: MUP je odbio kredencijale ili servisni token (401).
+	ErrUnauthorized = errors.New("mup: unauthorized")
+)
+
+// StatusError je odgovor van 2xx. Message je "error" polje iz tela odgovora
+// (ili pocetak tela ako nije JSON).
+type StatusError struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("mup %s %s: status %d", e.Method, e.Path, e.StatusCode)
+	}
+	return fmt.Sprintf("mup %s %s: status %d: %s", e.Method, e.Path, e.StatusCode, e.Message)
+}
+
+// Is omogucava errors.Is(err, ErrNotFound) i errors.Is(err, ErrUnauthorized).
+func (e *StatusError) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	}
+	return false
+}
+
+// TransportError znaci da MUP nije odgovorio (mreza, timeout, otkazan ctx).
+type TransportError struct {
+	Method string
+	Path   string
+	Err    error
+}
+
+func (e *TransportError) Error() string {
+	return fmt.Sprintf("mup %s %s: %v", e.Method, e.Path, e.Err)
+}
+
+func (e *TransportError) Unwrap() error { return e.Err }
+
+// DecodeError znaci da je MUP odgovorio 2xx, ali telo nije ocekivani JSON.
+type DecodeError struct {
+	Method string
+	Path   string
+	Err    error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("mup %s %s: decode response: %v", e.Method, e.Path, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error { return e.Err }
+
+// Unavailable kaze da MUP trenutno nije dostupan (nema odgovora, 5xx/429 ili
+// otvoren breaker), za razliku od odgovora kao sto je 404.
+func Unavailable(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || retryable(err)
+}
@@ -0,0 +1,109 @@
+package mupclient
+
+import "time"
+
+// DTO-i MUP registra (mup-vehicles), onakvi kakvi dolaze preko HTTP-a.
+
+type Owner struct {
+	ID        string `json:"id"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+	Address   string `json:"address"`
+	JMBG      string `json:"jmbg"`
+	Email     string `json:"email"`
+}
+
+type Vehicle struct {
+	ID           string `json:"id"`
+	Mark         string `json:"mark"`
+	Model        string `json:"model"`
+	Registration string `json:"registration"`
+	Year         int    `json:"year"`
+	Color        string `json:"color"`
+	IsStolen     bool   `json:"isStolen"`
+	OwnerID      string `json:"ownerId"`
+	Owner        Owner  `json:"owner"`
+}
+
+type Driver struct {
+	ID                      string `json:"id"`
+	IsSuspended             bool   `json:"isSuspended"`
+	NumberOfViolationPoints int    `json:"numberOfViolationPoints"`
+	Picture                 string `json:"picture"`
+	OwnerID                 string `json:"ownerId"`
+	Owner                   Owner  `json:"owner"`
+}
+
+// RegistryChanges je stranica snimka registra (Full) ili izmena od kursora.
+// Next trazi sledecu stranicu; posle poslednje se Cursor salje kao since.
+type RegistryChanges struct {
+	Full     bool      `json:"full"`
+	Cursor   time.Time `json:"cursor"`
+	Next     string    `json:"next,omitempty"`
+	Owners   []Owner   `json:"owners"`
+	Vehicles []Vehicle `json:"vehicles"`
+	Drivers  []Driver  `json:"drivers"`
+}
+
+// Suspension je zapis o suspenziji vozacke; Active racuna MUP.
+type Suspension struct {
+	ID        uint       `json:"id"`
+	DriverID  string     `json:"driverId"`
+	Source    string     `json:"source"`
+	Reason    string     `json:"reason"`
+	Authority string     `json:"authority"`
+	IssuedBy  string     `json:"issuedBy,omitempty"`
+	StartsAt  time.Time  `json:"startsAt"`
+	EndsAt    *time.Time `json:"endsAt,omitempty"`
+	LiftedAt  *time.Time `json:"liftedAt,omitempty"`
+	LiftedBy  string     `json:"liftedBy,omitempty"`
+	Active    bool       `json:"active"`
+}
+
+// PointsEntry je stavka istorije poena vozaca.
+type PointsEntry struct {
+	ID             uint      `json:"id"`
+	DriverID       string    `json:"driverId"`
+	ViolationID    string    `json:"violationId,omitempty"`
+	Delta          int       `json:"delta"`
+	Date           time.Time `json:"date"`
+	PointsAfter    int       `json:"pointsAfter"`
+	SuspendedAfter bool      `json:"suspendedAfter"`
+	ExpiresAt      time.Time `json:"expiresAt"`
+	Expired        bool      `json:"expired"`
+}
+
+// PointsRequest je promena poena. ViolationID (ili IdempotencyKey, koji se
+// salje kao zaglavlje) cini ponovljen zahtev bezbednim. Od Date tece
+// zastarelost poena.
+type PointsRequest struct {
+	Delta          int        `json:"delta"`
+	ViolationID    string     `json:"violationId,omitempty"`
+	Date           *time.Time `json:"date,omitempty"`
+	IdempotencyKey string     `json:"-"`
+}
+
+// SuspendRequest izrice (IsSuspended=true) ili ukida suspenzije vozaca.
+type SuspendRequest struct {
+	IsSuspended bool       `json:"isSuspended"`
+	Reason      string     `json:"reason,omitempty"`
+	StartsAt    *time.Time `json:"startsAt,omitempty"`
+	EndsAt      *time.Time `json:"endsAt,omitempty"`
+}
+
+// Vrste naloga koje vraca Login.
+const (
+	KindOwner = "OWNER"
+	KindAdmin = "ADMIN"
+)
+
+// Identity je nalog cije je kredencijale MUP potvrdio.
+type Identity struct {
+	Kind      string `json:"kind"`
+	ID        string `json:"id"`
+	DriverID  string `json:"driverId"`
+	JMBG      string `json:"jmbg"`
+	Email     string `json:"email"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
@@ -0,0 +1,142 @@
+// Package policy sprovodi deklarativna pravila pristupa po ruti
+// (uloga, minimalni cin, pristup samo sopstvenim zapisima).
+package policy
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+	"strings"
+
+	"common/jwtauth"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RankFunc vraca nivo cina pozivaoca (veci broj = visi cin).
+type RankFunc func(c *gin.Context, claims *jwtauth.Claims) (int, error)
+
+// SelfCheck proverava da li zahtev cilja zapis samog pozivaoca.
+type SelfCheck func(c *gin.Context, claims *jwtauth.Claims) (bool, error)
+
+type Rule struct {
+	// Public rute ne traze token.
+	Public bool
+	// Roles mogu da pozovu rutu bez dodatnih uslova.
+	Roles []string
+	// MinRank se primenjuje samo na saobracajnu policiju (TRAFFIC).
+	MinRank int
+	// SelfRoles mogu da pozovu rutu samo kad Self potvrdi da je zapis njihov.
+	SelfRoles []string
+	Self      SelfCheck
+}
+
+// Table mapira "METOD /putanja" (kao u gin ruteru) na pravilo.
+type Table map[string]Rule
+
+var Public = Rule{Public: true}
+
+func Allow(roles ...string) Rule {
+	return Rule{Roles: roles}
+}
+
+func (r Rule) WithMinRank(rank int) Rule {
+	r.MinRank = rank
+	return r
+}
+
+func (r Rule) OrSelf(check SelfCheck, roles ...string) Rule {
+	r.SelfRoles = roles
+	r.Self = check
+	return r
+}
+
+func key(method, path string) string {
+	return method + " " + path
+}
+
+// PublicPaths vraca putanje koje jwtauth.Middleware treba da propusti.
+func (t Table) PublicPaths() []string {
+	var out []string
+	for k, rule := range t {
+		if rule.Public {
+			_, path, _ := strings.Cut(k, " ")
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+// Verify vraca gresku ako neka registrovana ruta nema pravilo, da se
+// nova ruta ne bi slucajno ostavila otvorenom (ili zatvorenom).
+func (t Table) Verify(routes gin.RoutesInfo) error {
+	var missing []string
+	for _, r := range routes {
+		if _, ok := t[key(r.Method, r.Path)]; !ok {
+			missing = append(missing, key(r.Method, r.Path))
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("policy: no rule for routes: %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// Enforce vraca 403 kad pozivalac nema pravo na rutu. Mora da stoji posle
+// jwtauth.Middleware. Rute bez pravila su zabranjene.
+func Enforce(t Table, rank RankFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		rule, ok := t[key(c.Request.Method, c.FullPath())]
+		if ok && rule.Public {
+			c.Next()
+			return
+		}
+
+		claims, authenticated := jwtauth.FromContext(c)
+		if !authenticated {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "missing bearer token"})
+			return
+		}
+		if !ok {
+			forbid(c, "no access rule for route")
+			return
+		}
+
+		switch {
+		case slices.Contains(rule.Roles, claims.Role):
+		case slices.Contains(rule.SelfRoles, claims.Role) && rule.Self != nil:
+			own, err := rule.Self(c, claims)
+			if err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "access check failed"})
+				return
+			}
+			if !own {
+				forbid(c, "access limited to own records")
+				return
+			}
+		default:
+			forbid(c, "role not allowed")
+			return
+		}
+
+		if rule.MinRank > 0 && claims.Role == jwtauth.RoleTraffic {
+			if rank == nil {
+				forbid(c, "rank cannot be determined")
+				return
+			}
+			level, err := rank(c, claims)
+			if err != nil || level < rule.MinRank {
+				forbid(c, "insufficient rank")
+				return
+			}
+		}
+
+		c.Next()
+	}
+}
+
+func forbid(c *gin.Context, reason string) {
+	c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "forbidden", "reason": reason})
+}
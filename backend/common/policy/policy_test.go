@@ -0,0 +1,108 @@
+package policy_test
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"common/jwtauth"
+	"common/policy"
+	"common/policy/policytest"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestEnforce(t *testing.T) {
+	own := func(c *gin.Context, claims *jwtauth.Claims) (bool, error) {
+		switch c.Param("id") {
+		case "broken":
+			return false, errors.New("db down")
+		case claims.ID:
+			return true, nil
+		}
+		return false, nil
+	}
+	ranks := map[string]int{"senior": 3, "junior": 1}
+	rank := func(c *gin.Context, claims *jwtauth.Claims) (int, error) {
+		return ranks[claims.ID], nil
+	}
+
+	table := policy.Table{
+		"GET /health":     policy.Public,
+		"GET /things":     policy.Allow(jwtauth.RoleMup, jwtauth.RoleTraffic),
+		"GET /things/:id": policy.Allow(jwtauth.RoleMup).OrSelf(own, jwtauth.RoleCitizen),
+		"POST /things":    policy.Allow(jwtauth.RoleMup, jwtauth.RoleTraffic).WithMinRank(3),
+	}
+	r := policytest.New(t, table, rank)
+	r.GET("/unlisted", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	citizen := r.Token(jwtauth.Claims{Role: jwtauth.RoleCitizen, ID: "c1"})
+	mup := r.Token(jwtauth.Claims{Role: jwtauth.RoleMup, ID: "m1"})
+	senior := r.Token(jwtauth.Claims{Role: jwtauth.RoleTraffic, ID: "senior"})
+	junior := r.Token(jwtauth.Claims{Role: jwtauth.RoleTraffic, ID: "junior"})
+	service := r.Token(jwtauth.Claims{Role: jwtauth.RoleService, ID: "svc"})
+
+	tests := []struct {
+		name         string
+		method, path string
+		token        string
+		want         int
+	}{
+		{"public without token", "GET", "/health", "", 200},
+		{"no token", "GET", "/things", "", 401},
+		{"garbage token", "GET", "/things", "not-a-jwt", 401},
+		{"allowed role", "GET", "/things", mup, 200},
+		{"other role", "GET", "/things", citizen, 403},
+		{"service role", "GET", "/things", service, 403},
+		{"self on own record", "GET", "/things/c1", citizen, 200},
+		{"self on other record", "GET", "/things/c2", citizen, 403},
+		{"self check error", "GET", "/things/broken", citizen, 500},
+		{"role rule skips self check", "GET", "/things/c2", mup, 200},
+		{"rank high enough", "POST", "/things", senior, 200},
+		{"rank too low", "POST", "/things", junior, 403},
+		{"rank ignored for MUP", "POST", "/things", mup, 200},
+		{"route without rule", "GET", "/unlisted", mup, 403},
+		{"route without rule, no token", "GET", "/unlisted", "", 401},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Do(tt.method, tt.path, tt.token); got != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnforceMinRankWithoutRankFunc(t *testing.T) {
+	table := policy.Table{"POST /things": policy.Allow(jwtauth.RoleTraffic).WithMinRank(1)}
+	r := policytest.New(t, table, nil)
+
+	token := r.Token(jwtauth.Claims{Role: jwtauth.RoleTraffic, ID: "t1"})
+	if got := r.Do("POST", "/things", token); got != 403 {
+		t.Fatalf("status = %d, want 403 when rank cannot be determined", got)
+	}
+}
+
+func TestVerify(t *testing.T) {
+	routes := gin.RoutesInfo{
+		{Method: "GET", Path: "/things"},
+		{Method: "POST", Path: "/things"},
+		{Method: "GET", Path: "/things/:id"},
+	}
+	table := policy.Table{"GET /things": policy.Allow(jwtauth.RoleMup)}
+
+	err := table.Verify(routes)
+	if err == nil {
+		t.Fatal("Verify accepted routes without rules")
+	}
+	want := "policy: no rule for routes: GET /things/:id, POST /things"
+	if err.Error() != want {
+		t.Fatalf("err = %q, want %q", err, want)
+	}
+
+	table["POST /things"] = policy.Allow(jwtauth.RoleMup)
+	table["GET /things/:id"] = policy.Allow(jwtauth.RoleMup)
+	if err := table.Verify(routes); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,90 @@
+// Package policytest je pomoc za testove tabela pristupa: router sa istim
+// middleware-ima kao u servisima, gde svaka ruta odgovara 200, i potpisivanje
+// tokena za proizvoljne claim-ove.
+package policytest
+
+import (
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"common/jwtauth"
+	"common/policy"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	issuer = "policytest"
+	kid    = "policytest"
+)
+
+type staticKey struct{ pub ed25519.PublicKey }
+
+func (k staticKey) Key(_ context.Context, id string) (crypto.PublicKey, error) {
+	if id != kid {
+		return nil, jwtauth.ErrUnknownKey
+	}
+	return k.pub, nil
+}
+
+type Router struct {
+	*gin.Engine
+	t    testing.TB
+	priv ed25519.PrivateKey
+}
+
+// New registruje sve rute iz tabele iza jwtauth.Middleware i policy.Enforce.
+// Dodatne rute (npr. bez pravila) mogu da se registruju na vracenom ruteru.
+func New(t testing.TB, table policy.Table, rank policy.RankFunc) *Router {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := &Router{Engine: gin.New(), t: t, priv: priv}
+	r.Use(
+		jwtauth.Middleware(jwtauth.Config{Issuer: issuer, Keys: staticKey{pub}}, table.PublicPaths()...),
+		policy.Enforce(table, rank),
+	)
+	for k := range table {
+		method, path, _ := strings.Cut(k, " ")
+		r.Handle(method, path, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+	return r
+}
+
+// Token potpisuje access token sa datim claim-ovima koji vazi jedan minut.
+func (r *Router) Token(claims jwtauth.Claims) string {
+	r.t.Helper()
+	claims.Issuer = issuer
+	claims.ExpiresAt = jwt.NewNumericDate(time.Now().Add(time.Minute))
+	tok := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	tok.Header["kid"] = kid
+	s, err := tok.SignedString(r.priv)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return s
+}
+
+// Do salje zahtev sa Bearer tokenom (bez zaglavlja kad je token prazan) i
+// vraca status odgovora.
+func (r *Router) Do(method, path, token string) int {
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w.Code
+}
@@ -16,6 +16,12 @@ type Config struct {
 	DBName       string
 	MupBaseURL   string
 	MupTimeoutMs int
+	Issuer       string
+	// JWKSURL je adresa javnih kljuceva auth servisa.
+	JWKSURL  string
+	SeedData bool
+	// PointsExpiryDays je posle koliko dana kazneni poeni zastarevaju.
+	PointsExpiryDays int
 }
 
 func GetConfig() Config {
@@ -29,6 +35,11 @@ func GetConfig() Config {
 		mup = "http://mup-vehicles-service:8081"
 	}
 
+	authURL := os.Getenv("AUTH_BASE_URL")
+	if authURL == "" {
+		authURL = "http://auth-service:8080"
+	}
+
 	timeoutMs := 3000
 	if v := os.Getenv("MUP_TIMEOUT_MS"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil {
@@ -36,8 +47,26 @@ func GetConfig() Config {
 		}
 	}
 
+	dbPort := 5432
+	if v := os.Getenv("DB_PORT"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			dbPort = n
+		}
+	}
+
+	expiryDays := 730
+	if v := os.Getenv("POINTS_EXPIRY_DAYS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			expiryDays = n
+		}
+	}
+
+	// SEED_DATA=true upisuje deterministicki test registar pri startu
+	seed, _ := strconv.ParseBool(os.Getenv("SEED_DATA"))
+
 	return Config{
 		DBHost:       os.Getenv("DB_HOST"),
+		DBPort:       dbPort,
 		DBUser:       os.Getenv("DB_USER"),
 		DBPass:       os.Getenv("DB_PASS"),
 		DBName:       os.Getenv("DB_NAME"),
@@ -45,5 +74,10 @@ func GetConfig() Config {
 		ServicePort:  port,
 		MupBaseURL:   mup,
 		MupTimeoutMs: timeoutMs,
+		Issuer:       os.Getenv("ISSUER"),
+		JWKSURL:      authURL + "/.well-known/jwks.json",
+		SeedData:     seed,
+
+		PointsExpiryDays: expiryDays,
 	}
 }
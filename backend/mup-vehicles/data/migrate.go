@@ -0,0 +1,30 @@
+package data
+
+import (
+	"common/migrate"
+	"fmt"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// TablePrefix odvaja MUP registar od istoimenih tabela traffic-police
+// servisa (owners, vehicles...) posto svi servisi dele istu bazu.
+const TablePrefix = "mup_"
+
+func InitDB(host, user, password, dbname string, port int) (*gorm.DB, error) {
+	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%d sslmode=disable", host, user, password, dbname, port)
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{TablePrefix: TablePrefix},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return db, nil
+}
+
+// Migrator vraca runner za migracije mup-vehicles servisa (vidi migrations.go).
+func Migrator(db *gorm.DB) migrate.Runner {
+	return migrate.New(db, "mup-vehicles", migrations)
+}
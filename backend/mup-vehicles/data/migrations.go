@@ -0,0 +1,258 @@
+package data
+
+import "common/migrate"
+
+// Imena tabela moraju da prate TablePrefix iz InitDB-a.
+var migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "create_registry",
+		Up: `
+CREATE TABLE IF NOT EXISTS mup_administrators (
+	id         text PRIMARY KEY,
+	first_name text,
+	last_name  text,
+	email      text,
+	password   text,
+	created_at timestamptz,
+	updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mup_administrators_email ON mup_administrators (email);
+
+CREATE TABLE IF NOT EXISTS mup_owners (
+	id         text PRIMARY KEY,
+	first_name text,
+	last_name  text,
+	address    text,
+	jmbg       text,
+	email      text,
+	password   text,
+	created_at timestamptz,
+	updated_at timestamptz
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mup_owners_jmbg ON mup_owners (jmbg);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mup_owners_email ON mup_owners (email);
+
+CREATE TABLE IF NOT EXISTS mup_driver_ids (
+	id                         text PRIMARY KEY,
+	is_suspended               boolean,
+	number_of_violation_points bigint,
+	picture                    text,
+	owner_id                   text,
+	created_at                 timestamptz,
+	updated_at                 timestamptz,
+	CONSTRAINT fk_mup_driver_ids_owner FOREIGN KEY (owner_id) REFERENCES mup_owners (id)
+);
+CREATE INDEX IF NOT EXISTS idx_mup_driver_ids_owner_id ON mup_driver_ids (owner_id);
+
+CREATE TABLE IF NOT EXISTS mup_vehicles (
+	id           text PRIMARY KEY,
+	mark         text,
+	model        text,
+	registration text,
+	year         bigint,
+	color        text,
+	is_stolen    boolean,
+	owner_id     text,
+	created_at   timestamptz,
+	updated_at   timestamptz,
+	CONSTRAINT fk_mup_vehicles_owner FOREIGN KEY (owner_id) REFERENCES mup_owners (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mup_vehicles_registration ON mup_vehicles (registration);
+CREATE INDEX IF NOT EXISTS idx_mup_vehicles_owner_id ON mup_vehicles (owner_id);
+
+CREATE TABLE IF NOT EXISTS mup_ownership_transfers (
+	id               text PRIMARY KEY,
+	vehicle_id       text,
+	old_owner_id     text,
+	new_owner_id     text,
+	date_of_transfer timestamptz,
+	created_at       timestamptz,
+	updated_at       timestamptz,
+	CONSTRAINT fk_mup_ownership_transfers_vehicle FOREIGN KEY (vehicle_id) REFERENCES mup_vehicles (id),
+	CONSTRAINT fk_mup_ownership_transfers_old_owner FOREIGN KEY (old_owner_id) REFERENCES mup_owners (id),
+	CONSTRAINT fk_mup_ownership_transfers_new_owner FOREIGN KEY (new_owner_id) REFERENCES mup_owners (id)
+);
+CREATE INDEX IF NOT EXISTS idx_mup_ownership_transfers_vehicle_id ON mup_ownership_transfers (vehicle_id);
+CREATE INDEX IF NOT EXISTS idx_mup_ownership_transfers_old_owner_id ON mup_ownership_transfers (old_owner_id);
+CREATE INDEX IF NOT EXISTS idx_mup_ownership_transfers_new_owner_id ON mup_ownership_transfers (new_owner_id);
+`,
+		Down: `
+DROP TABLE IF EXISTS mup_ownership_transfers;
+DROP TABLE IF EXISTS mup_vehicles;
+DROP TABLE IF EXISTS mup_driver_ids;
+DROP TABLE IF EXISTS mup_owners;
+DROP TABLE IF EXISTS mup_administrators;
+`,
+	},
+	{
+		Version: 2,
+		Name:    "create_points_entries",
+		Up: `
+CREATE TABLE IF NOT EXISTS mup_points_entries (
+	id              bigserial PRIMARY KEY,
+	driver_id       text NOT NULL,
+	violation_id    text NOT NULL DEFAULT '',
+	idempotency_key text NOT NULL DEFAULT '',
+	delta           bigint NOT NULL,
+	points_after    bigint NOT NULL,
+	suspended_after boolean NOT NULL,
+	created_at      timestamptz,
+	CONSTRAINT fk_mup_points_entries_driver FOREIGN KEY (driver_id) REFERENCES mup_driver_ids (id)
+);
+CREATE INDEX IF NOT EXISTS idx_mup_points_entries_driver_id ON mup_points_entries (driver_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mup_points_entries_key ON mup_points_entries (driver_id, idempotency_key)
+	WHERE idempotency_key <> '';
+`,
+		Down: `
+DROP TABLE IF EXISTS mup_points_entries;
+`,
+	},
+	{
+		Version: 3,
+		Name:    "points_expiry",
+		// postojeci poeni bez stavki dobijaju pocetnu stavku, da bi zbir
+		// stavki odgovarao stanju vozacke
+		Up: `
+ALTER TABLE mup_points_entries ADD COLUMN IF NOT EXISTS date timestamptz;
+UPDATE mup_points_entries SET date = created_at WHERE date IS NULL;
+ALTER TABLE mup_points_entries ALTER COLUMN date SET NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_mup_points_entries_driver_date ON mup_points_entries (driver_id, date);
+
+ALTER TABLE mup_driver_ids ADD COLUMN IF NOT EXISTS suspended_by_points boolean NOT NULL DEFAULT false;
+UPDATE mup_driver_ids SET suspended_by_points = true
+	WHERE is_suspended AND number_of_violation_points >= 10;
+
+INSERT INTO mup_points_entries (driver_id, idempotency_key, delta, date, points_after, suspended_after, created_at)
+SELECT d.id, 'opening-balance', d.number_of_violation_points - COALESCE(SUM(e.delta), 0),
+	now(), d.number_of_violation_points, COALESCE(d.is_suspended, false), now()
+FROM mup_driver_ids d
+LEFT JOIN mup_points_entries e ON e.driver_id = d.id
+GROUP BY d.id
+HAVING d.number_of_violation_points - COALESCE(SUM(e.delta), 0) <> 0;
+`,
+		Down: `
+DELETE FROM mup_points_entries WHERE idempotency_key = 'opening-balance';
+ALTER TABLE mup_driver_ids DROP COLUMN IF EXISTS suspended_by_points;
+DROP INDEX IF EXISTS idx_mup_points_entries_driver_date;
+ALTER TABLE mup_points_entries DROP COLUMN IF EXISTS date;
+`,
+	},
+	{
+		Version: 4,
+		Name:    "create_suspensions",
+		// postojece suspenzije postaju zapisi bez roka; suspended_by_points
+		// zamenjuje source zapisa
+		Up: `
+CREATE TABLE IF NOT EXISTS mup_suspensions (
+	id         bigserial PRIMARY KEY,
+	driver_id  text NOT NULL,
+	source     text NOT NULL,
+	reason     text NOT NULL,
+	authority  text NOT NULL,
+	issued_by  text NOT NULL DEFAULT '',
+	starts_at  timestamptz NOT NULL,
+	ends_at    timestamptz,
+	lifted_at  timestamptz,
+	lifted_by  text NOT NULL DEFAULT '',
+	created_at timestamptz,
+	CONSTRAINT fk_mup_suspensions_driver FOREIGN KEY (driver_id) REFERENCES mup_driver_ids (id)
+);
+CREATE INDEX IF NOT EXISTS idx_mup_suspensions_driver_id ON mup_suspensions (driver_id);
+
+INSERT INTO mup_suspensions (driver_id, source, reason, authority, starts_at, created_at)
+SELECT id,
+	CASE WHEN suspended_by_points THEN 'POINTS' ELSE 'MANUAL' END,
+	CASE WHEN suspended_by_points THEN 'violation points reached the suspension threshold'
+		ELSE 'suspended before suspension records were kept' END,
+	CASE WHEN suspended_by_points THEN 'SYSTEM' ELSE 'MUP' END,
+	COALESCE(updated_at, now()), now()
+FROM mup_driver_ids
+WHERE is_suspended;
+
+ALTER TABLE mup_driver_ids DROP COLUMN IF EXISTS suspended_by_points;
+`,
+		Down: `
+ALTER TABLE mup_driver_ids ADD COLUMN IF NOT EXISTS suspended_by_points boolean NOT NULL DEFAULT false;
+UPDATE mup_driver_ids d SET suspended_by_points = true
+	WHERE EXISTS (
+		SELECT 1 FROM mup_suspensions s
+		WHERE s.driver_id = d.id AND s.source = 'POINTS' AND s.lifted_at IS NULL
+	);
+DROP TABLE IF EXISTS mup_suspensions;
+`,
+	},
+	{
+		Version: 5,
+		Name:    "registry_updated_at_indexes",
+		// izmene registra od kursora (GET /registry/changes) se traze po updated_at
+		Up: `
+UPDATE mup_owners SET updated_at = COALESCE(created_at, now()) WHERE updated_at IS NULL;
+UPDATE mup_vehicles SET updated_at = COALESCE(created_at, now()) WHERE updated_at IS NULL;
+UPDATE mup_driver_ids SET updated_at = COALESCE(created_at, now()) WHERE updated_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_mup_owners_updated_at ON mup_owners (updated_at);
+CREATE INDEX IF NOT EXISTS idx_mup_vehicles_updated_at ON mup_vehicles (updated_at);
+CREATE INDEX IF NOT EXISTS idx_mup_driver_ids_updated_at ON mup_driver_ids (updated_at);
+`,
+		Down: `
+DROP INDEX IF EXISTS idx_mup_driver_ids_updated_at;
+DROP INDEX IF EXISTS idx_mup_vehicles_updated_at;
+DROP INDEX IF EXISTS idx_mup_owners_updated_at;
+`,
+	},
+	{
+		Version: 6,
+		Name:    "transfer_workflow",
+		// postojeci prenosi su izvrseni; vozilo moze imati najvise jedan
+		// prenos na cekanju
+		Up: `
+ALTER TABLE mup_ownership_transfers
+	ADD COLUMN IF NOT EXISTS status text NOT NULL DEFAULT 'COMPLETED',
+	ADD COLUMN IF NOT EXISTS seller_confirmed_at timestamptz,
+	ADD COLUMN IF NOT EXISTS buyer_confirmed_at timestamptz,
+	ADD COLUMN IF NOT EXISTS cancelled_at timestamptz,
+	ADD COLUMN IF NOT EXISTS cancelled_by text NOT NULL DEFAULT '',
+	ADD COLUMN IF NOT EXISTS cancel_reason text NOT NULL DEFAULT '';
+ALTER TABLE mup_ownership_transfers ALTER COLUMN status DROP DEFAULT;
+UPDATE mup_ownership_transfers
+	SET seller_confirmed_at = date_of_transfer, buyer_confirmed_at = date_of_transfer
+	WHERE status = 'COMPLETED' AND seller_confirmed_at IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_mup_ownership_transfers_pending
+	ON mup_ownership_transfers (vehicle_id) WHERE status = 'PENDING';
+CREATE SEQUENCE IF NOT EXISTS mup_ownership_transfer_seq START 1000;
+`,
+		Down: `
+DROP SEQUENCE IF EXISTS mup_ownership_transfer_seq;
+DROP INDEX IF EXISTS idx_mup_ownership_transfers_pending;
+DELETE FROM mup_ownership_transfers WHERE status <> 'COMPLETED';
+ALTER TABLE mup_ownership_transfers
+	DROP COLUMN IF EXISTS cancel_reason,
+	DROP COLUMN IF EXISTS cancelled_by,
+	DROP COLUMN IF EXISTS cancelled_at,
+	DROP COLUMN IF EXISTS buyer_confirmed_at,
+	DROP COLUMN IF EXISTS seller_confirmed_at,
+	DROP COLUMN IF EXISTS status;
+`,
+	},
+	{
+		Version: 7,
+		Name:    "registry_page_indexes",
+		// stranice izmena registra idu po (updated_at, id)
+		Up: `
+CREATE INDEX IF NOT EXISTS idx_mup_owners_updated_at_id ON mup_owners (updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_mup_vehicles_updated_at_id ON mup_vehicles (updated_at, id);
+CREATE INDEX IF NOT EXISTS idx_mup_driver_ids_updated_at_id ON mup_driver_ids (updated_at, id);
+DROP INDEX IF EXISTS idx_mup_owners_updated_at;
+DROP INDEX IF EXISTS idx_mup_vehicles_updated_at;
+DROP INDEX IF EXISTS idx_mup_driver_ids_updated_at;
+`,
+		Down: `
+CREATE INDEX IF NOT EXISTS idx_mup_owners_updated_at ON mup_owners (updated_at);
+CREATE INDEX IF NOT EXISTS idx_mup_vehicles_updated_at ON mup_vehicles (updated_at);
+CREATE INDEX IF NOT EXISTS idx_mup_driver_ids_updated_at ON mup_driver_ids (updated_at);
+DROP INDEX IF EXISTS idx_mup_driver_ids_updated_at_id;
+DROP INDEX IF EXISTS idx_mup_vehicles_updated_at_id;
+DROP INDEX IF EXISTS idx_mup_owners_updated_at_id;
+`,
+	},
+}
@@ -0,0 +1,38 @@
+package data
+
+import (
+	"mup-vehicles/models"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// HashPlaintextPasswords hesira lozinke koje su ranije upisane kao obican
+// tekst (stari seed). Vec hesirane lozinke ("$2...") se preskacu.
+func HashPlaintextPasswords(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []any{&models.Owner{}, &models.Administrator{}} {
+			var rows []struct {
+				ID       string
+				Password string
+			}
+			if err := tx.Model(model).
+				Where("password <> '' AND password NOT LIKE ?", "$2%").
+				Select("id", "password").
+				Scan(&rows).Error; err != nil {
+				return err
+			}
+			for _, row := range rows {
+				hash, err := bcrypt.GenerateFromPassword([]byte(row.Password), bcrypt.DefaultCost)
+				if err != nil {
+					return err
+				}
+				if err := tx.Model(model).Where("id = ?", row.ID).
+					Update("password", string(hash)).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
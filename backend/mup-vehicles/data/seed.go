@@ -0,0 +1,213 @@
+package data
+
+import (
+	"fmt"
+	"math/rand"
+	"mup-vehicles/models"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// fixtureSeed je fiksan da bi svako pokretanje dalo isti registar.
+const fixtureSeed = 8
+
+// FixturePassword je lozinka svih vlasnika i administratora iz fixture-a.
+// U bazu se upisuje kao bcrypt hash.
+const FixturePassword = "123"
+
+// fixtureDate je referentni datum za prenose vlasnistva u fixture-u.
+var fixtureDate = time.Date(2025, time.January, 15, 10, 0, 0, 0, time.UTC)
+
+// Fixtures generise test registar (vlasnici, vozila, vozacke, admini, prenosi).
+// Rezultat je uvek isti za isti fixtureSeed.
+func Fixtures() ([]models.Owner, []models.Vehicle, []models.DriverId, []models.Administrator, []models.OwnershipTransfer) {
+	rnd := rand.New(rand.NewSource(fixtureSeed))
+
+	firstNames := []string{"Marko", "Jovan", "Ana", "Milica", "Petar", "Nikola", "Ivana", "Stefan", "Mina", "Luka"}
+	lastNames := []string{"Markovic", "Jovanovic", "Petrovic", "Nikolic", "Ilic", "Savic", "Stojanovic", "Kovacevic"}
+	streets := []string{"Bulevar Oslobodjenja", "Cara Dusana", "Zmaj Jovina", "Bulevar Evrope", "Narodnog Fronta", "Temerinska", "Kralja Petra"}
+
+	marks := []string{"Audi", "BMW", "Volkswagen", "Skoda", "Opel", "Toyota", "Peugeot", "Renault"}
+	modelsByMark := map[string][]string{
+		"Audi":       {"A3", "A4", "A6"},
+		"BMW":        {"320d", "X3", "X5"},
+		"Volkswagen": {"Golf", "Passat", "Polo"},
+		"Skoda":      {"Octavia", "Fabia", "Superb"},
+		"Opel":       {"Astra", "Corsa", "Insignia"},
+		"Toyota":     {"Corolla", "Yaris", "RAV4"},
+		"Peugeot":    {"208", "308", "3008"},
+		"Renault":    {"Clio", "Megane", "Kadjar"},
+	}
+	colors := []string{"Black", "White", "Gray", "Blue", "Red", "Silver"}
+
+	// --- owners ---
+	owners := make([]models.Owner, 0, 8)
+	for i := 0; i < 8; i++ {
+		fn := firstNames[rnd.Intn(len(firstNames))]
+		ln := lastNames[rnd.Intn(len(lastNames))]
+		jmbg := fmt.Sprintf("0%d0%d99%05d%02d", rnd.Intn(9)+1, rnd.Intn(9)+1, rnd.Intn(99999), i+1)
+
+		owners = append(owners, models.Owner{
+			ID:        fmt.Sprintf("OWN-%d", i+1),
+			FirstName: fn,
+			LastName:  ln,
+			Address:   fmt.Sprintf("%s %d", streets[rnd.Intn(len(streets))], rnd.Intn(99)+1),
+			JMBG:      jmbg,
+			Email: fmt.Sprintf("%s.%s%d@mail.com",
+				strings.ToLower(fn),
+				strings.ToLower(ln),
+				i+1),
+			Password: FixturePassword,
+		})
+	}
+
+	// --- vehicles ---
+	vehicles := make([]models.Vehicle, 0, 10)
+	regs := []string{"NS-123-AB", "NS-456-CD", "BG-111-AA", "BG-222-BB", "SU-777-ZZ", "NI-333-CC", "KG-999-DD", "ZR-101-EE", "PA-202-FF", "SM-303-GG"}
+	for i := 0; i < 10; i++ {
+		mk := marks[rnd.Intn(len(marks))]
+		md := modelsByMark[mk][rnd.Intn(len(modelsByMark[mk]))]
+
+		vehicles = append(vehicles, models.Vehicle{
+			ID:           fmt.Sprintf("VEH-%d", i+1),
+			Mark:         mk,
+			Model:        md,
+			Registration: regs[i%len(regs)],
+			Year:         2008 + rnd.Intn(17),
+			Color:        colors[rnd.Intn(len(colors))],
+			IsStolen:     rnd.Intn(10) == 0,
+			OwnerID:      owners[rnd.Intn(len(owners))].ID,
+		})
+	}
+
+	// --- drivers ---
+	drivers := make([]models.DriverId, 0, 8)
+	for i := 0; i < 8; i++ {
+		points := rnd.Intn(12)
+
+		drivers = append(drivers, models.DriverId{
+			ID:                      fmt.Sprintf("DRV-%d", i+1),
+			IsSuspended:             points >= 10,
+			NumberOfViolationPoints: points,
+			Picture:                 fmt.Sprintf("driver%d.jpg", i+1),
+			OwnerID:                 owners[i%len(owners)].ID,
+		})
+	}
+
+	// --- admins ---
+	admins := []models.Administrator{
+		{
+			ID:        "ADM-1",
+			FirstName: "Admin",
+			LastName:  "MUP",
+			Email:     "admin@mup.rs",
+			Password:  FixturePassword,
+		},
+		{
+			ID:        "ADM-2",
+			FirstName: "Supervisor",
+			LastName:  "MUP",
+			Email:     "supervisor@mup.rs",
+			Password:  FixturePassword,
+		},
+	}
+
+	// --- transfers ---
+	// novi vlasnik je uvek trenutni vlasnik vozila, da bi istorija bila konzistentna
+	transfers := make([]models.OwnershipTransfer, 0, 3)
+	for i := 0; i < 3; i++ {
+		veh := vehicles[rnd.Intn(len(vehicles))]
+		oldOwner := owners[rnd.Intn(len(owners))]
+		for oldOwner.ID == veh.OwnerID {
+			oldOwner = owners[rnd.Intn(len(owners))]
+		}
+
+		date := fixtureDate.AddDate(0, -rnd.Intn(12), -rnd.Intn(28))
+		transfers = append(transfers, models.OwnershipTransfer{
+			ID:                fmt.Sprintf("TRA-%d", i+1),
+			VehicleID:         veh.ID,
+			OldOwnerID:        oldOwner.ID,
+			NewOwnerID:        veh.OwnerID,
+			Status:            models.TransferCompleted,
+			SellerConfirmedAt: &date,
+			BuyerConfirmedAt:  &date,
+			DateOfTransfer:    &date,
+		})
+	}
+
+	return owners, vehicles, drivers, admins, transfers
+}
+
+// Seed upisuje fixture u bazu. Postojeci redovi (isti ID) se ne diraju,
+// tako da je bezbedno pozvati ga na svakom startu.
+func Seed(db *gorm.DB) error {
+	owners, vehicles, drivers, admins, transfers := Fixtures()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(FixturePassword), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	for i := range owners {
+		owners[i].Password = string(hash)
+	}
+	for i := range admins {
+		admins[i].Password = string(hash)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		skip := tx.Clauses(clause.OnConflict{DoNothing: true}).Omit(clause.Associations)
+
+		if err := skip.Create(&owners).Error; err != nil {
+			return err
+		}
+		if err := skip.Create(&vehicles).Error; err != nil {
+			return err
+		}
+		if err := skip.Create(&drivers).Error; err != nil {
+			return err
+		}
+		if entries := openingEntries(drivers); len(entries) > 0 {
+			if err := skip.Create(&entries).Error; err != nil {
+				return err
+			}
+		}
+		// suspendovani iz fixture-a dobijaju zapis o suspenziji po poenima
+		if err := tx.Exec(`
+INSERT INTO mup_suspensions (driver_id, source, reason, authority, starts_at, created_at)
+SELECT d.id, ?, ?, ?, ?, now()
+FROM mup_driver_ids d
+WHERE d.is_suspended AND NOT EXISTS (SELECT 1 FROM mup_suspensions s WHERE s.driver_id = d.id)`,
+			models.SuspensionPoints, "violation points reached the suspension threshold",
+			models.AuthoritySystem, fixtureDate).Error; err != nil {
+			return err
+		}
+		if err := skip.Create(&admins).Error; err != nil {
+			return err
+		}
+		return skip.Create(&transfers).Error
+	})
+}
+
+// openingEntries pravi pocetnu stavku istorije za poene iz fixture-a, posto
+// se NumberOfViolationPoints racuna iz stavki.
+func openingEntries(drivers []models.DriverId) []models.PointsEntry {
+	entries := make([]models.PointsEntry, 0, len(drivers))
+	for _, d := range drivers {
+		if d.NumberOfViolationPoints == 0 {
+			continue
+		}
+		entries = append(entries, models.PointsEntry{
+			DriverID:       d.ID,
+			IdempotencyKey: "opening-balance",
+			Delta:          d.NumberOfViolationPoints,
+			Date:           fixtureDate,
+			PointsAfter:    d.NumberOfViolationPoints,
+			SuspendedAfter: d.IsSuspended,
+		})
+	}
+	return entries
+}
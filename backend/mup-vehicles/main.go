@@ -1,207 +1,93 @@
 package main
 
 import (
+	"common/jwtauth"
+	"common/policy"
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"math/rand"
 	"mup-vehicles/config"
-	"strings"
+	"mup-vehicles/data"
+	"mup-vehicles/models"
+	"mup-vehicles/service"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-//
-// ===== MODELS =====
-//
-
-type Administrator struct {
-	ID        string `json:"id"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-}
-
-type Owner struct {
-	ID        string `json:"id"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Address   string `json:"address"`
-	JMBG      string `json:"jmbg"`
-	Email     string `json:"email"`
-	Password  string `json:"password"`
-}
-
-type DriverId struct {
-	ID                      string `json:"id"`
-	IsSuspended             bool   `json:"isSuspended"`
-	NumberOfViolationPoints int    `json:"numberOfViolationPoints"`
-	Picture                 string `json:"picture"`
-	Owner                   Owner  `json:"owner"`
-}
-
-type Vehicle struct {
-	ID           string `json:"id"`
-	Mark         string `json:"mark"`
-	Model        string `json:"model"`
-	Registration string `json:"registration"`
-	Year         int    `json:"year"`
-	Color        string `json:"color"`
-	IsStolen     bool   `json:"isStolen"`
-	Owner        Owner  `json:"owner"`
-}
-
-type OwnershipTransfer struct {
-	ID             string    `json:"id"`
-	Vehicle        Vehicle   `json:"vehicle"`
-	OldOwner       Owner     `json:"oldOwner"`
-	NewOwner       Owner     `json:"newOwner"`
-	DateOfTransfer time.Time `json:"dateOfTransfer"`
+// notFoundOr500 vraca 404 ako zapis ne postoji, a 500 za ostale greske baze.
+func notFoundOr500(c *gin.Context, err error, what string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(404, gin.H{"error": what + " not found"})
+		return
+	}
+	c.JSON(500, gin.H{"error": err.Error()})
 }
 
-type LoginRequest struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+// transferError mapira greske prenosa vlasnistva: 400 za neispravan zahtev,
+// 403 kad potvrdjuje neko ko nije strana, 409 kad stanje vozila ili prenosa
+// ne dozvoljava korak.
+func transferError(c *gin.Context, err error, what string) {
+	switch {
+	case errors.Is(err, service.ErrTransferInvalid):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrNotParty), errors.Is(err, service.ErrNotOwner):
+		c.JSON(403, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrVehicleStolen), errors.Is(err, service.ErrTransferOpen),
+		errors.Is(err, service.ErrTransferClosed), errors.Is(err, service.ErrOwnerChanged):
+		c.JSON(409, gin.H{"error": err.Error()})
+	default:
+		notFoundOr500(c, err, what)
+	}
 }
 
-//
-// ===== MOCK DATA =====
-//
-
-var owners []Owner
-var vehicles []Vehicle
-var drivers []DriverId
-var transfers []OwnershipTransfer
-var admins []Administrator
-
-func seedData() {
-	rand.Seed(time.Now().UnixNano())
-
-	firstNames := []string{"Marko", "Jovan", "Ana", "Milica", "Petar", "Nikola", "Ivana", "Stefan", "Mina", "Luka"}
-	lastNames := []string{"Markovic", "Jovanovic", "Petrovic", "Nikolic", "Ilic", "Savic", "Stojanovic", "Kovacevic"}
-	streets := []string{"Bulevar Oslobodjenja", "Cara Dusana", "Zmaj Jovina", "Bulevar Evrope", "Narodnog Fronta", "Temerinska", "Kralja Petra"}
-	passwords := []string{"123", "123", "123", "123", "123", "123", "123"}
-
-	marks := []string{"Audi", "BMW", "Volkswagen", "Skoda", "Opel", "Toyota", "Peugeot", "Renault"}
-	modelsByMark := map[string][]string{
-		"Audi":       {"A3", "A4", "A6"},
-		"BMW":        {"320d", "X3", "X5"},
-		"Volkswagen": {"Golf", "Passat", "Polo"},
-		"Skoda":      {"Octavia", "Fabia", "Superb"},
-		"Opel":       {"Astra", "Corsa", "Insignia"},
-		"Toyota":     {"Corolla", "Yaris", "RAV4"},
-		"Peugeot":    {"208", "308", "3008"},
-		"Renault":    {"Clio", "Megane", "Kadjar"},
-	}
-	colors := []string{"Black", "White", "Gray", "Blue", "Red", "Silver"}
-
-	// --- owners ---
-	owners = make([]Owner, 0, 8)
-	for i := 0; i < 8; i++ {
-		fn := firstNames[rand.Intn(len(firstNames))]
-		ln := lastNames[rand.Intn(len(lastNames))]
-		pw := passwords[rand.Intn(len(passwords))]
-		jmbg := fmt.Sprintf("0%d0%d99%05d%02d", rand.Intn(9)+1, rand.Intn(9)+1, rand.Intn(99999), i+1)
-
-		o := Owner{
-			ID:        fmt.Sprintf("OWN-%d", i+1), // Simple String ID
-			FirstName: fn,
-			LastName:  ln,
-			Address:   fmt.Sprintf("%s %d", streets[rand.Intn(len(streets))], rand.Intn(99)+1),
-			JMBG:      jmbg,
-			Email: fmt.Sprintf("%s.%s%d@mail.com",
-				strings.ToLower(fn),
-				strings.ToLower(ln),
-				i+1), Password: pw,
-		}
-		owners = append(owners, o)
-	}
+func main() {
+	cfg := config.GetConfig()
 
-	// --- vehicles ---
-	vehicles = make([]Vehicle, 0, 10)
-	regs := []string{"NS-123-AB", "NS-456-CD", "BG-111-AA", "BG-222-BB", "SU-777-ZZ", "NI-333-CC", "KG-999-DD", "ZR-101-EE", "PA-202-FF", "SM-303-GG"}
-	for i := 0; i < 10; i++ {
-		mk := marks[rand.Intn(len(marks))]
-		md := modelsByMark[mk][rand.Intn(len(modelsByMark[mk]))]
-		yr := 2008 + rand.Intn(17)
-		stolen := rand.Intn(10) == 0
-
-		v := Vehicle{
-			ID:           fmt.Sprintf("VEH-%d", i+1), // Simple String ID
-			Mark:         mk,
-			Model:        md,
-			Registration: regs[i%len(regs)],
-			Year:         yr,
-			Color:        colors[rand.Intn(len(colors))],
-			IsStolen:     stolen,
-			Owner:        owners[rand.Intn(len(owners))],
-		}
-		vehicles = append(vehicles, v)
+	// DB
+	db, err := data.InitDB(cfg.DBHost, cfg.DBUser, cfg.DBPass, cfg.DBName, cfg.DBPort)
+	if err != nil {
+		panic(fmt.Sprintf("Failed to connect to database: %v", err))
 	}
+	migrator := data.Migrator(db)
 
-	// --- drivers ---
-	drivers = make([]DriverId, 0, 8)
-	for i := 0; i < 8; i++ {
-		points := rand.Intn(12)
-		susp := points >= 10
-
-		d := DriverId{
-			ID:                      fmt.Sprintf("DRV-%d", i+1), // Simple String ID
-			IsSuspended:             susp,
-			NumberOfViolationPoints: points,
-			Picture:                 fmt.Sprintf("driver%d.jpg", i+1),
-			Owner:                   owners[i%len(owners)],
+	// "server migrate up|down [n]|status" samo izvrsava migracije i izlazi
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrator.Command(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
 		}
-		drivers = append(drivers, d)
+		return
 	}
-
-	// --- admins ---
-	admins = []Administrator{
-		{
-			ID:        "ADM-1",
-			FirstName: "Admin",
-			LastName:  "MUP",
-			Email:     "admin@mup.rs",
-			Password:  "123",
-		},
-		{
-			ID:        "ADM-2",
-			FirstName: "Supervisor",
-			LastName:  "MUP",
-			Email:     "supervisor@mup.rs",
-			Password:  "123",
-		},
+	if _, err = migrator.Up(context.Background()); err != nil {
+		panic(fmt.Sprintf("Failed to migrate database: %v", err))
 	}
-
-	// --- transfers ---
-	transfers = make([]OwnershipTransfer, 0, 3)
-	for i := 0; i < 3; i++ {
-		oldOwner := owners[rand.Intn(len(owners))]
-		newOwner := owners[rand.Intn(len(owners))]
-		for newOwner.ID == oldOwner.ID {
-			newOwner = owners[rand.Intn(len(owners))]
-		}
-		veh := vehicles[rand.Intn(len(vehicles))]
-
-		t := OwnershipTransfer{
-			ID:             fmt.Sprintf("TRA-%d", i+1), // Simple String ID
-			Vehicle:        veh,
-			OldOwner:       oldOwner,
-			NewOwner:       newOwner,
-			DateOfTransfer: time.Now().AddDate(0, -rand.Intn(12), -rand.Intn(28)),
+	if err = data.HashPlaintextPasswords(db); err != nil {
+		panic(fmt.Sprintf("Failed to hash passwords: %v", err))
+	}
+	if cfg.SeedData {
+		if err = data.Seed(db); err != nil {
+			panic(fmt.Sprintf("Failed to seed database: %v", err))
 		}
-		transfers = append(transfers, t)
 	}
-}
 
-// ... rest of main() and routes stay exactly as they were ...
+	store := service.NewStore(db)
+	store.PointsExpiry = time.Duration(cfg.PointsExpiryDays) * 24 * time.Hour
+	go store.RunPointsExpiryJob(context.Background(), 24*time.Hour)
+	go store.RunSuspensionJob(context.Background(), time.Hour)
 
-func main() {
-	seedData()
-	cfg := config.GetConfig()
+	jwtCfg := jwtauth.Config{Issuer: cfg.Issuer, Keys: jwtauth.NewRemoteKeySet(cfg.JWKSURL)}
+
+	routes := routePolicy(store)
 
 	r := gin.Default()
+	r.Use(
+		jwtauth.Middleware(jwtCfg, routes.PublicPaths()...),
+		policy.Enforce(routes, nil),
+	)
 
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"service": "mup-vehicles", "status": "ok"})
@@ -210,182 +96,341 @@ func main() {
 	// ===== VEHICLES =====
 
 	r.GET("/vehicles", func(c *gin.Context) {
-		c.JSON(200, vehicles)
+		var list []models.Vehicle
+		if err := store.ListVehicles(&list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
 	})
 
 	r.GET("/vehicles/:registration", func(c *gin.Context) {
-		reg := c.Param("registration")
-		for _, v := range vehicles {
-			if v.Registration == reg {
-				c.JSON(200, v)
-				return
-			}
+		var v models.Vehicle
+		if err := store.GetVehicleByRegistration(c.Param("registration"), &v); err != nil {
+			notFoundOr500(c, err, "vehicle")
+			return
 		}
-		c.JSON(404, gin.H{"error": "vehicle not found"})
+		c.JSON(200, v)
 	})
 
 	// endpoint koji drugi servis moze da koristi
 	r.GET("/vehicles/owner/:jmbg", func(c *gin.Context) {
-		jmbg := c.Param("jmbg")
-		for _, v := range vehicles {
-			if v.Owner.JMBG == jmbg {
-				c.JSON(200, v)
-				return
-			}
+		var v models.Vehicle
+		if err := store.GetVehicleByOwnerJMBG(c.Param("jmbg"), &v); err != nil {
+			notFoundOr500(c, err, "vehicle")
+			return
 		}
-		c.JSON(404, gin.H{"error": "vehicle not found"})
+		c.JSON(200, v)
+	})
+
+	r.GET("/me/vehicles", func(c *gin.Context) {
+		claims, _ := jwtauth.FromContext(c)
+		list := []models.Vehicle{}
+		if claims == nil || claims.JMBG == "" {
+			c.JSON(200, list)
+			return
+		}
+		if err := store.ListVehiclesByOwnerJMBG(claims.JMBG, &list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
 	})
 
 	// ===== DRIVERS =====
 
 	r.GET("/drivers", func(c *gin.Context) {
-		c.JSON(200, drivers)
+		var list []models.DriverId
+		if err := store.ListDrivers(&list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
 	})
 
 	r.GET("/drivers/:id", func(c *gin.Context) {
-		id := c.Param("id")
-		for _, d := range drivers {
-			if d.ID == id {
-				c.JSON(200, d)
-				return
-			}
+		var d models.DriverId
+		if err := store.GetDriver(c.Param("id"), &d); err != nil {
+			notFoundOr500(c, err, "driver")
+			return
 		}
-		c.JSON(404, gin.H{"error": "driver not found"})
+		c.JSON(200, d)
 	})
 
-	// PATCH /drivers/:id/points   body: { "delta": 2 }
-	type PointsUpdateRequest struct {
-		Delta int `json:"delta"`
-	}
-
+	// PATCH /drivers/:id/points   body: { "delta": 2, "violationId": "..." }
+	// Idempotency-Key (ili violationId) sprecava dvostruko racunanje pri ponavljanju
 	r.PATCH("/drivers/:id/points", func(c *gin.Context) {
-		id := c.Param("id")
-
-		var req PointsUpdateRequest
+		var req models.PointsUpdateRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
 
-		for i := range drivers {
-			if drivers[i].ID == id {
-				drivers[i].NumberOfViolationPoints += req.Delta
-				if drivers[i].NumberOfViolationPoints < 0 {
-					drivers[i].NumberOfViolationPoints = 0
-				}
-
-				// prag (menjaj po potrebi)
-				if drivers[i].NumberOfViolationPoints >= 10 {
-					drivers[i].IsSuspended = true
-				}
-
-				c.JSON(200, drivers[i])
+		key := service.PointsKey(c.GetHeader("Idempotency-Key"), req.ViolationID, req.Delta)
+		var d models.DriverId
+		replayed, err := store.AddDriverPoints(c.Param("id"), req, key, &d)
+		if err != nil {
+			if errors.Is(err, service.ErrIdempotencyMismatch) {
+				c.JSON(422, gin.H{"error": err.Error()})
 				return
 			}
+			notFoundOr500(c, err, "driver")
+			return
+		}
+		if replayed {
+			c.Header("Idempotent-Replayed", "true")
 		}
+		c.JSON(200, d)
+	})
 
-		c.JSON(404, gin.H{"error": "driver not found"})
+	r.GET("/drivers/:id/points/history", func(c *gin.Context) {
+		var d models.DriverId
+		if err := store.GetDriver(c.Param("id"), &d); err != nil {
+			notFoundOr500(c, err, "driver")
+			return
+		}
+		list := []models.PointsEntry{}
+		if err := store.ListPointsEntries(d.ID, &list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
 	})
 
 	r.GET("/drivers/email/:email", func(c *gin.Context) {
-		email := c.Param("email")
-		fmt.Printf("[MUP] GET /drivers/email/%s\n", email)
-
-		// izvuci ime: "milica.savic1@mail.com" → "milica"
-		localPart := email
-		if idx := strings.Index(email, "@"); idx != -1 {
-			localPart = email[:idx]
-		}
-		firstName := localPart
-		if idx := strings.Index(localPart, "."); idx != -1 {
-			firstName = localPart[:idx]
-		}
-		fmt.Printf("[MUP] searching by firstName: %s\n", firstName)
-
-		for _, d := range drivers {
-			if strings.EqualFold(d.Owner.FirstName, firstName) {
-				fmt.Printf("[MUP] ✅ Found: %s %s (id=%s)\n", d.Owner.FirstName, d.Owner.LastName, d.ID)
-				// uvek vrati password "123" da auth moze da proveri
-				d.Owner.Password = "123"
-				c.JSON(200, d)
-				return
-			}
+		var d models.DriverId
+		if err := store.GetDriverByOwnerEmail(c.Param("email"), &d); err != nil {
+			notFoundOr500(c, err, "driver")
+			return
 		}
-
-		fmt.Printf("[MUP] ❌ Not found for firstName: %s\n", firstName)
-		c.JSON(404, gin.H{"error": "driver not found"})
+		c.JSON(200, d)
 	})
 
+	// POST /login proverava kredencijale za auth servis (federisani login)
 	r.POST("/login", func(c *gin.Context) {
-		var req LoginRequest
-		if err := c.ShouldBindJSON(&req); err != nil {
+		var req models.LoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil || req.Email == "" || req.Password == "" {
 			c.JSON(400, gin.H{"error": "invalid payload"})
 			return
 		}
 
-		fmt.Printf("[MUP] POST /login — email: %s\n", req.Email)
+		var out models.LoginResponse
+		if err := store.VerifyCredentials(req.Email, req.Password, &out); err != nil {
+			if errors.Is(err, service.ErrInvalidCredentials) {
+				c.JSON(401, gin.H{"error": "invalid credentials"})
+				return
+			}
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, out)
+	})
 
-		for _, a := range admins {
-			if a.Email == req.Email && a.Password == req.Password {
-				fmt.Printf("[MUP] ✅ Admin login: %s\n", a.Email)
-				c.JSON(200, gin.H{"id": a.ID, "email": a.Email, "role": "CITIZEN"})
+	// PATCH /drivers/:id/suspend
+	// body: { "isSuspended": true, "reason": "...", "startsAt": "...", "endsAt": "..." }
+	// isSuspended=false ukida sve aktivne suspenzije
+	r.PATCH("/drivers/:id/suspend", func(c *gin.Context) {
+		var req models.SuspendRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		claims, _ := jwtauth.FromContext(c)
+		var d models.DriverId
+		var err error
+		if req.IsSuspended {
+			err = store.Suspend(c.Param("id"), req, claims.Role, claims.ID, &d)
+		} else {
+			err = store.LiftSuspensions(c.Param("id"), claims.ID, &d)
+		}
+		if err != nil {
+			if errors.Is(err, service.ErrSuspensionReason) || errors.Is(err, service.ErrSuspensionTerm) {
+				c.JSON(400, gin.H{"error": err.Error()})
 				return
 			}
+			notFoundOr500(c, err, "driver")
+			return
 		}
+		c.JSON(200, d)
+	})
 
-		for _, d := range drivers {
-			fmt.Printf("[MUP] checking driver owner: %s (pass: %s)\n", d.Owner.Email, d.Owner.Password)
-			if d.Owner.Email == req.Email && d.Owner.Password == req.Password {
-				fmt.Printf("[MUP] ✅ Driver login: %s\n", d.Owner.Email)
-				c.JSON(200, d)
+	r.GET("/drivers/:id/suspensions", func(c *gin.Context) {
+		var d models.DriverId
+		if err := store.GetDriver(c.Param("id"), &d); err != nil {
+			notFoundOr500(c, err, "driver")
+			return
+		}
+		list := []models.Suspension{}
+		if err := store.ListSuspensions(d.ID, &list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
+	})
+
+	// ===== OWNERS =====
+	r.GET("/owners", func(c *gin.Context) {
+		var list []models.Owner
+		if err := store.ListOwners(&list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
+	})
+
+	// auth servis ovim proverava da li email vec pripada MUP vlasniku
+	r.GET("/owners/email/:email", func(c *gin.Context) {
+		var o models.Owner
+		if err := store.GetOwnerByEmail(c.Param("email"), &o); err != nil {
+			notFoundOr500(c, err, "owner")
+			return
+		}
+		c.JSON(200, o)
+	})
+
+	// ===== REGISTRY =====
+	// GET /registry/changes?since=<cursor>&limit=<n>  bez since vraca ceo
+	// registar; sledeca stranica: ?page=<next>
+	r.GET("/registry/changes", func(c *gin.Context) {
+		var since time.Time
+		if v := c.Query("since"); v != "" {
+			t, err := time.Parse(time.RFC3339Nano, v)
+			if err != nil {
+				c.JSON(400, gin.H{"error": "since must be an RFC 3339 timestamp"})
 				return
 			}
+			since = t
 		}
 
-		fmt.Printf("[MUP] ❌ No match found for email: %s\n", req.Email)
-		c.JSON(401, gin.H{"error": "invalid credentials"})
+		limit, _ := strconv.Atoi(c.Query("limit"))
+
+		var out models.RegistryChanges
+		if err := store.RegistryChanges(since, c.Query("page"), limit, &out); err != nil {
+			if errors.Is(err, service.ErrRegistryPage) {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, out)
 	})
 
-	// PATCH /drivers/:id/suspend  body: { "isSuspended": true/false }
-	type SuspendRequest struct {
-		IsSuspended bool `json:"isSuspended"`
-	}
+	// ===== TRANSFERS =====
+	// Prodavac podnese zahtev, kupac (i prodavac, ako vec nije) potvrdi, i
+	// tada se vlasnik vozila menja. Strane su MUP vlasnici iz tokena (owner_id).
+	r.GET("/transfers", func(c *gin.Context) {
+		list := []models.OwnershipTransfer{}
+		if err := store.ListTransfers(models.TransferStatus(c.Query("status")), &list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
+	})
 
-	r.PATCH("/drivers/:id/suspend", func(c *gin.Context) {
-		id := c.Param("id")
+	r.GET("/transfers/:id", func(c *gin.Context) {
+		var t models.OwnershipTransfer
+		if err := store.GetTransfer(c.Param("id"), &t); err != nil {
+			notFoundOr500(c, err, "transfer")
+			return
+		}
+		c.JSON(200, t)
+	})
 
-		var req SuspendRequest
+	// POST /transfers   body: { "registration": "...", "buyerJmbg": "..." }
+	r.POST("/transfers", func(c *gin.Context) {
+		var req models.TransferRequest
 		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
 
-		for i := range drivers {
-			if drivers[i].ID == id {
-				drivers[i].IsSuspended = req.IsSuspended
-				c.JSON(200, drivers[i])
+		claims, _ := jwtauth.FromContext(c)
+		var t models.OwnershipTransfer
+		if err := store.RequestTransfer(req, claims.OwnerID, &t); err != nil {
+			transferError(c, err, "vehicle")
+			return
+		}
+		c.JSON(201, t)
+	})
+
+	r.PATCH("/transfers/:id/confirm", func(c *gin.Context) {
+		claims, _ := jwtauth.FromContext(c)
+		var t models.OwnershipTransfer
+		if err := store.ConfirmTransfer(c.Param("id"), claims.OwnerID, &t); err != nil {
+			transferError(c, err, "transfer")
+			return
+		}
+		c.JSON(200, t)
+	})
+
+	// PATCH /transfers/:id/cancel   body: { "reason": "..." } (opciono)
+	r.PATCH("/transfers/:id/cancel", func(c *gin.Context) {
+		var req models.CancelTransferRequest
+		if c.Request.ContentLength != 0 {
+			if err := c.ShouldBindJSON(&req); err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
 				return
 			}
 		}
 
-		c.JSON(404, gin.H{"error": "driver not found"})
+		// gradjanin otkazuje kao vlasnik (strana u prenosu), MUP kao sluzbenik
+		claims, _ := jwtauth.FromContext(c)
+		by := claims.ID
+		if claims.Role == jwtauth.RoleCitizen {
+			by = claims.OwnerID
+		}
+		var t models.OwnershipTransfer
+		if err := store.CancelTransfer(c.Param("id"), by, req.Reason, &t); err != nil {
+			transferError(c, err, "transfer")
+			return
+		}
+		c.JSON(200, t)
 	})
 
-	// ===== OWNERS =====
-	r.GET("/owners", func(c *gin.Context) {
-		c.JSON(200, owners)
+	r.GET("/vehicles/:registration/transfers", func(c *gin.Context) {
+		var v models.Vehicle
+		if err := store.GetVehicleByRegistration(c.Param("registration"), &v); err != nil {
+			notFoundOr500(c, err, "vehicle")
+			return
+		}
+		list := []models.OwnershipTransfer{}
+		if err := store.ListVehicleTransfers(v.Registration, &list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
 	})
 
-	// ===== TRANSFERS =====
-	r.GET("/transfers", func(c *gin.Context) {
-		c.JSON(200, transfers)
+	r.GET("/me/transfers", func(c *gin.Context) {
+		claims, _ := jwtauth.FromContext(c)
+		list := []models.OwnershipTransfer{}
+		if claims == nil || claims.OwnerID == "" {
+			c.JSON(200, list)
+			return
+		}
+		if err := store.ListOwnerTransfers(claims.OwnerID, &list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
 	})
 
 	// ===== ADMINS =====
 	r.GET("/admins", func(c *gin.Context) {
-		c.JSON(200, admins)
+		var list []models.Administrator
+		if err := store.ListAdmins(&list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
 	})
 
+	if err := routes.Verify(r.Routes()); err != nil {
+		panic(err)
+	}
+
 	addr := fmt.Sprintf("%s:%d", cfg.ServiceHost, cfg.ServicePort)
 	if err := r.Run(addr); err != nil {
 		log.Fatal("greska prilikom pokretanja servera: ", err)
@@ -0,0 +1,216 @@
+package models
+
+import (
+	"time"
+)
+
+//
+// ===== DB Models =====
+//
+
+// ID-jevi su citljivi stringovi (OWN-1, VEH-1, DRV-1...) jer ih ostali
+// servisi koriste direktno u putanjama i cuvaju u svojim tabelama.
+
+type Administrator struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:text"`
+	FirstName string    `json:"firstName"`
+	LastName  string    `json:"lastName"`
+	Email     string    `json:"email" gorm:"uniqueIndex"`
+	Password  string    `json:"-"` // bcrypt hash
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type Owner struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:text"`
+	FirstName string    `json:"firstName"`
+	LastName  string    `json:"lastName"`
+	Address   string    `json:"address"`
+	JMBG      string    `json:"jmbg" gorm:"uniqueIndex"`
+	Email     string    `json:"email" gorm:"uniqueIndex"`
+	Password  string    `json:"-"` // bcrypt hash
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// DriverId: IsSuspended se racuna iz aktivnih Suspension zapisa vozaca pri
+// svakom citanju; sacuvana kolona se osvezava uz promene i periodicno.
+type DriverId struct {
+	ID                      string    `json:"id" gorm:"primaryKey;type:text"`
+	IsSuspended             bool      `json:"isSuspended"`
+	NumberOfViolationPoints int       `json:"numberOfViolationPoints"`
+	Picture                 string    `json:"picture"`
+	OwnerID                 string    `json:"ownerId" gorm:"index"`
+	Owner                   Owner     `json:"owner" gorm:"foreignKey:OwnerID;references:ID"`
+	CreatedAt               time.Time `json:"createdAt"`
+	UpdatedAt               time.Time `json:"updatedAt"`
+}
+
+type Vehicle struct {
+	ID           string    `json:"id" gorm:"primaryKey;type:text"`
+	Mark         string    `json:"mark"`
+	Model        string    `json:"model"`
+	Registration string    `json:"registration" gorm:"uniqueIndex"`
+	Year         int       `json:"year"`
+	Color        string    `json:"color"`
+	IsStolen     bool      `json:"isStolen"`
+	OwnerID      string    `json:"ownerId" gorm:"index"`
+	Owner        Owner     `json:"owner" gorm:"foreignKey:OwnerID;references:ID"`
+	CreatedAt    time.Time `json:"createdAt"`
+	UpdatedAt    time.Time `json:"updatedAt"`
+}
+
+type TransferStatus string
+
+const (
+	TransferPending   TransferStatus = "PENDING"
+	TransferCompleted TransferStatus = "COMPLETED"
+	TransferCancelled TransferStatus = "CANCELLED"
+)
+
+// OwnershipTransfer je prenos vlasnistva sa prodavca (OldOwner) na kupca
+// (NewOwner). Zahtev podnosi prodavac; vlasnik se menja kad oba potvrde, i
+// tada DateOfTransfer dobija vrednost.
+type OwnershipTransfer struct {
+	ID                string         `json:"id" gorm:"primaryKey;type:text"`
+	VehicleID         string         `json:"vehicleId" gorm:"index"`
+	Vehicle           Vehicle        `json:"vehicle" gorm:"foreignKey:VehicleID;references:ID"`
+	OldOwnerID        string         `json:"oldOwnerId" gorm:"index"`
+	OldOwner          Owner          `json:"oldOwner" gorm:"foreignKey:OldOwnerID;references:ID"`
+	NewOwnerID        string         `json:"newOwnerId" gorm:"index"`
+	NewOwner          Owner          `json:"newOwner" gorm:"foreignKey:NewOwnerID;references:ID"`
+	Status            TransferStatus `json:"status" gorm:"type:text"`
+	SellerConfirmedAt *time.Time     `json:"sellerConfirmedAt,omitempty"`
+	BuyerConfirmedAt  *time.Time     `json:"buyerConfirmedAt,omitempty"`
+	DateOfTransfer    *time.Time     `json:"dateOfTransfer,omitempty"`
+	CancelledAt       *time.Time     `json:"cancelledAt,omitempty"`
+	CancelledBy       string         `json:"cancelledBy,omitempty"`
+	CancelReason      string         `json:"cancelReason,omitempty"`
+	CreatedAt         time.Time      `json:"createdAt"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+}
+
+// PointsEntry je jedna promena poena vozaca; NumberOfViolationPoints je zbir
+// nezastarelih stavki. Date je dan prekrsaja i od njega tece zastarelost.
+// IdempotencyKey sprecava da se ista promena primeni dvaput; PointsAfter i
+// SuspendedAfter cuvaju rezultat koji se vraca za ponovljen zahtev.
+type PointsEntry struct {
+	ID             uint      `json:"id" gorm:"primaryKey"`
+	DriverID       string    `json:"driverId" gorm:"index"`
+	ViolationID    string    `json:"violationId,omitempty"`
+	IdempotencyKey string    `json:"-"`
+	Delta          int       `json:"delta"`
+	Date           time.Time `json:"date"`
+	PointsAfter    int       `json:"pointsAfter"`
+	SuspendedAfter bool      `json:"suspendedAfter"`
+	CreatedAt      time.Time `json:"createdAt"`
+
+	// racuna se pri citanju iz roka zastarelosti
+	ExpiresAt time.Time `json:"expiresAt" gorm:"-"`
+	Expired   bool      `json:"expired" gorm:"-"`
+}
+
+type SuspensionSource string
+
+const (
+	// SuspensionPoints nastaje kad poeni dostignu prag i traje dok ne padnu ispod njega.
+	SuspensionPoints SuspensionSource = "POINTS"
+	// SuspensionManual izrice MUP (ili drugi servis), sa rokom ili bez njega.
+	SuspensionManual SuspensionSource = "MANUAL"
+)
+
+// AuthoritySystem je izdavalac suspenzija koje servis sam izrice i ukida.
+const AuthoritySystem = "SYSTEM"
+
+// Suspension je jedna suspenzija vozacke. Aktivna je od StartsAt do EndsAt
+// (nil znaci do ukidanja), osim ako je ranije ukinuta (LiftedAt).
+type Suspension struct {
+	ID        uint             `json:"id" gorm:"primaryKey"`
+	DriverID  string           `json:"driverId" gorm:"index"`
+	Source    SuspensionSource `json:"source" gorm:"type:text"`
+	Reason    string           `json:"reason"`
+	Authority string           `json:"authority"`
+	IssuedBy  string           `json:"issuedBy,omitempty"`
+	StartsAt  time.Time        `json:"startsAt"`
+	EndsAt    *time.Time       `json:"endsAt,omitempty"`
+	LiftedAt  *time.Time       `json:"liftedAt,omitempty"`
+	LiftedBy  string           `json:"liftedBy,omitempty"`
+	CreatedAt time.Time        `json:"createdAt"`
+
+	Active bool `json:"active" gorm:"-"`
+}
+
+// ActiveAt kaze da li suspenzija vazi u trenutku at.
+func (s *Suspension) ActiveAt(at time.Time) bool {
+	return s.LiftedAt == nil && !s.StartsAt.After(at) && (s.EndsAt == nil || s.EndsAt.After(at))
+}
+
+//
+// ===== Request / DTO structs (ne migriraju se) =====
+//
+
+type LoginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Vrste naloga koje POST /login potvrdjuje.
+const (
+	KindOwner = "OWNER"
+	KindAdmin = "ADMIN"
+)
+
+// LoginResponse je identitet koji POST /login vraca auth servisu. ID je ID
+// vlasnika ili administratora, DriverID je prazan ako vlasnik nema vozacku.
+type LoginResponse struct {
+	Kind      string `json:"kind"`
+	ID        string `json:"id"`
+	DriverID  string `json:"driverId,omitempty"`
+	JMBG      string `json:"jmbg,omitempty"`
+	Email     string `json:"email"`
+	FirstName string `json:"firstName"`
+	LastName  string `json:"lastName"`
+}
+
+// PATCH /drivers/:id/points   body: { "delta": 2, "violationId": "..." }
+// Bez Idempotency-Key zaglavlja kljuc se izvodi iz violationId i znaka delte.
+// Date je dan prekrsaja (podrazumevano sada).
+type PointsUpdateRequest struct {
+	Delta       int        `json:"delta"`
+	ViolationID string     `json:"violationId"`
+	Date        *time.Time `json:"date"`
+}
+
+// PATCH /drivers/:id/suspend
+// body: { "isSuspended": true, "reason": "...", "startsAt": "...", "endsAt": "..." }
+// isSuspended=false ukida sve aktivne suspenzije vozaca.
+type SuspendRequest struct {
+	IsSuspended bool       `json:"isSuspended"`
+	Reason      string     `json:"reason"`
+	StartsAt    *time.Time `json:"startsAt"`
+	EndsAt      *time.Time `json:"endsAt"`
+}
+
+// RegistryChanges je stranica vlasnika, vozila i vozaca izmenjenih od kursora
+// (ili celog registra kad je Full). Next trazi sledecu stranicu; posle
+// poslednje se Cursor salje kao since u sledecem zahtevu.
+type RegistryChanges struct {
+	Full     bool       `json:"full"`
+	Cursor   time.Time  `json:"cursor"`
+	Next     string     `json:"next,omitempty"`
+	Owners   []Owner    `json:"owners"`
+	Vehicles []Vehicle  `json:"vehicles"`
+	Drivers  []DriverId `json:"drivers"`
+}
+
+// POST /transfers   body: { "registration": "NS-123-AB", "buyerJmbg": "..." }
+// Prodavac je vlasnik iz tokena.
+type TransferRequest struct {
+	Registration string `json:"registration"`
+	BuyerJMBG    string `json:"buyerJmbg"`
+}
+
+// PATCH /transfers/:id/cancel   body: { "reason": "..." }
+type CancelTransferRequest struct {
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+
+	"common/jwtauth"
+	"common/policy"
+	"mup-vehicles/models"
+	"mup-vehicles/service"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// routePolicy je tabela pristupa za sve rute servisa. Izmena poena i
+// suspenzije je dozvoljena samo MUP-u i drugim servisima (SERVICE token).
+func routePolicy(store *service.Store) policy.Table {
+	const (
+		citizen = jwtauth.RoleCitizen
+		mup     = jwtauth.RoleMup
+		traffic = jwtauth.RoleTraffic
+		svc     = jwtauth.RoleService
+	)
+
+	// transferParty: gradjanin je prodavac ili kupac u prenosu
+	transferParty := func(c *gin.Context, claims *jwtauth.Claims) (bool, error) {
+		var t models.OwnershipTransfer
+		if err := store.GetTransfer(c.Param("id"), &t); err != nil {
+			return false, lookupErr(err)
+		}
+		return claims.OwnerID != "" && (claims.OwnerID == t.OldOwnerID || claims.OwnerID == t.NewOwnerID), nil
+	}
+
+	return policy.Table{
+		"GET /health": policy.Public,
+
+		// Vehicles
+		"GET /vehicles":               policy.Allow(mup, traffic, svc),
+		"GET /vehicles/:registration": policy.Allow(mup, traffic, svc),
+		"GET /vehicles/owner/:jmbg":   policy.Allow(mup, traffic, svc),
+
+		// Gradjanin vidi samo svoje, JMBG dolazi iz tokena
+		"GET /me/vehicles": policy.Allow(citizen),
+
+		// Drivers
+		"GET /drivers":                    policy.Allow(mup, traffic, svc),
+		"GET /drivers/:id":                policy.Allow(mup, traffic, svc),
+		"GET /drivers/email/:email":       policy.Allow(mup, svc),
+		"PATCH /drivers/:id/points":       policy.Allow(mup, svc),
+		"GET /drivers/:id/points/history": policy.Allow(mup, traffic, svc),
+		"PATCH /drivers/:id/suspend":      policy.Allow(mup, svc),
+		"GET /drivers/:id/suspensions":    policy.Allow(mup, traffic, svc),
+
+		// Provera kredencijala gradjana radi auth servis
+		"POST /login": policy.Allow(svc),
+
+		// Kopija registra za druge servise (sinhronizacija)
+		"GET /registry/changes": policy.Allow(mup, svc),
+
+		"GET /owners":              policy.Allow(mup, traffic),
+		"GET /owners/email/:email": policy.Allow(mup, svc),
+		"GET /admins":              policy.Allow(mup),
+
+		// Transfers: zahtev podnosi prodavac, potvrdjuju obe strane
+		"GET /transfers":                        policy.Allow(mup, traffic),
+		"GET /transfers/:id":                    policy.Allow(mup, traffic).OrSelf(transferParty, citizen),
+		"POST /transfers":                       policy.Allow(citizen),
+		"PATCH /transfers/:id/confirm":          policy.Allow().OrSelf(transferParty, citizen),
+		"PATCH /transfers/:id/cancel":           policy.Allow(mup).OrSelf(transferParty, citizen),
+		"GET /vehicles/:registration/transfers": policy.Allow(mup, traffic, svc),
+		"GET /me/transfers":                     policy.Allow(citizen),
+	}
+}
+
+// lookupErr: nepostojeci zapis nije zapis pozivaoca (403), a ostale greske
+// baze se prosledjuju da bi Enforce odgovorio sa 500.
+func lookupErr(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	return err
+}
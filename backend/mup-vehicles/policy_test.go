@@ -0,0 +1,102 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"common/dbtest"
+	"common/jwtauth"
+	"common/policy/policytest"
+	"mup-vehicles/data"
+	"mup-vehicles/models"
+	"mup-vehicles/service"
+)
+
+// newPolicyStore pravi store sa prenosom T1 vozila X1 sa vlasnika O1 na O2,
+// koji proverava transferParty.
+func newPolicyStore(t *testing.T) *service.Store {
+	t.Helper()
+	db := dbtest.Open(t, data.TablePrefix, &models.Owner{}, &models.Vehicle{}, &models.OwnershipTransfer{})
+	dbtest.Insert(t, db,
+		&models.Owner{ID: "O1", JMBG: "0101990710001", Email: "o1@mup.test"},
+		&models.Owner{ID: "O2", JMBG: "0202990710002", Email: "o2@mup.test"},
+		&models.Vehicle{ID: "X1", Registration: "BG-001-AA", OwnerID: "O1"},
+		&models.OwnershipTransfer{ID: "T1", VehicleID: "X1", OldOwnerID: "O1", NewOwnerID: "O2", Status: models.TransferPending},
+	)
+	return service.NewStore(db)
+}
+
+func TestRoutePolicy(t *testing.T) {
+	store := newPolicyStore(t)
+	routes := routePolicy(store)
+	r := policytest.New(t, routes, nil)
+
+	// bez tokena prolazi samo ono sto je javno
+	for k, rule := range routes {
+		method, path, _ := strings.Cut(k, " ")
+		want := 401
+		if rule.Public {
+			want = 200
+		}
+		if got := r.Do(method, path, ""); got != want {
+			t.Errorf("anonymous %s = %d, want %d", k, got, want)
+		}
+	}
+
+	seller := r.Token(jwtauth.Claims{Role: jwtauth.RoleCitizen, ID: "u1", OwnerID: "O1"})
+	buyer := r.Token(jwtauth.Claims{Role: jwtauth.RoleCitizen, ID: "u2", OwnerID: "O2"})
+	stranger := r.Token(jwtauth.Claims{Role: jwtauth.RoleCitizen, ID: "u3", OwnerID: "O3"})
+	unlinked := r.Token(jwtauth.Claims{Role: jwtauth.RoleCitizen, ID: "u4"})
+	mup := r.Token(jwtauth.Claims{Role: jwtauth.RoleMup, ID: "m1"})
+	traffic := r.Token(jwtauth.Claims{Role: jwtauth.RoleTraffic, ID: "p1"})
+	svc := r.Token(jwtauth.Claims{Role: jwtauth.RoleService, ID: "auth"})
+
+	tests := []struct {
+		name         string
+		method, path string
+		token        string
+		want         int
+	}{
+		// OrSelf: gradjanin je strana u prenosu
+		{"seller confirms", "PATCH", "/transfers/T1/confirm", seller, 200},
+		{"buyer confirms", "PATCH", "/transfers/T1/confirm", buyer, 200},
+		{"stranger confirms", "PATCH", "/transfers/T1/confirm", stranger, 403},
+		{"citizen without owner confirms", "PATCH", "/transfers/T1/confirm", unlinked, 403},
+		{"unknown transfer", "PATCH", "/transfers/T404/confirm", seller, 403},
+		{"MUP cannot confirm", "PATCH", "/transfers/T1/confirm", mup, 403},
+		{"MUP cancels", "PATCH", "/transfers/T1/cancel", mup, 200},
+		{"buyer cancels", "PATCH", "/transfers/T1/cancel", buyer, 200},
+		{"traffic cannot cancel", "PATCH", "/transfers/T1/cancel", traffic, 403},
+		{"buyer reads transfer", "GET", "/transfers/T1", buyer, 200},
+		{"stranger reads transfer", "GET", "/transfers/T1", stranger, 403},
+
+		// izmena poena i suspenzija: samo MUP i servisi
+		{"service adds points", "PATCH", "/drivers/D1/points", svc, 200},
+		{"traffic adds points", "PATCH", "/drivers/D1/points", traffic, 403},
+		{"citizen suspends", "PATCH", "/drivers/D1/suspend", seller, 403},
+
+		// SERVICE token nema pristup listama vlasnika ni administraciji
+		{"service verifies login", "POST", "/login", svc, 200},
+		{"MUP verifies login", "POST", "/login", mup, 403},
+		{"service lists owners", "GET", "/owners", svc, 403},
+		{"service lists admins", "GET", "/admins", svc, 403},
+		{"service lists transfers", "GET", "/transfers", svc, 403},
+		{"service reads own vehicles", "GET", "/me/vehicles", svc, 403},
+		{"citizen lists vehicles", "GET", "/vehicles", seller, 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Do(tt.method, tt.path, tt.token); got != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// greska baze nije zabrana
+	if err := store.DB.Migrator().DropTable(&models.OwnershipTransfer{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Do("PATCH", "/transfers/T1/confirm", seller); got != 500 {
+		t.Errorf("transfer lookup failure = %d, want 500", got)
+	}
+}
@@ -0,0 +1,183 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"mup-vehicles/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// DefaultPointsExpiry: kazneni poeni zastarevaju posle dve godine.
+const DefaultPointsExpiry = 730 * 24 * time.Hour
+
+// ErrIdempotencyMismatch: kljuc je vec iskoriscen za drugaciju promenu.
+var ErrIdempotencyMismatch = errors.New("idempotency key reused with a different request")
+
+// PointsKey vraca kljuc promene: zadati kljuc, ili ga izvodi iz prekrsaja i
+// znaka delte (dodavanje i vracanje poena za isti prekrsaj su dve promene).
+func PointsKey(key, violationID string, delta int) string {
+	if key != "" || violationID == "" {
+		return key
+	}
+	if delta < 0 {
+		return "violation:" + violationID + ":reverse"
+	}
+	return "violation:" + violationID
+}
+
+// activePoints je zbir stavki vozaca koje nisu zastarele u trenutku now.
+func (s *Store) activePoints(tx *gorm.DB, driverID string, now time.Time) (int, error) {
+	var sum int
+	err := tx.Model(&models.PointsEntry{}).
+		Select("COALESCE(SUM(delta), 0)").
+		Where("driver_id = ? AND date > ?", driverID, now.Add(-s.PointsExpiry)).
+		Scan(&sum).Error
+	return max(sum, 0), err
+}
+
+// entryDate je dan od kog tece zastarelost stavke. Vracanje poena dobija dan
+// stavke koju ponistava, da bi obe zastarele zajedno.
+func entryDate(tx *gorm.DB, driverID, violationID string, delta int, date *time.Time) (time.Time, error) {
+	if delta < 0 && violationID != "" {
+		var orig models.PointsEntry
+		res := tx.Where("driver_id = ? AND violation_id = ? AND delta > 0", driverID, violationID).
+			Order("id").Limit(1).Find(&orig)
+		if res.Error != nil {
+			return time.Time{}, res.Error
+		}
+		if res.RowsAffected > 0 {
+			return orig.Date, nil
+		}
+	}
+	if date != nil && !date.IsZero() {
+		return *date, nil
+	}
+	return time.Now(), nil
+}
+
+// AddDriverPoints upisuje promenu od delta poena (moze biti negativna) u
+// istoriju, preracunava zbir nezastarelih poena i izrice ili ukida suspenziju
+// po poenima (prag je SuspensionThreshold). Red vozaca je zakljucan tokom izmene da
+// paralelni prekrsaji ne bi pregazili jedan drugog. Za vec vidjen kljuc nista
+// se ne menja, vraca se ranije stanje i replayed=true.
+func (s *Store) AddDriverPoints(id string, req models.PointsUpdateRequest, key string, out *models.DriverId) (replayed bool, err error) {
+	err = s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(out, "id = ?", id).Error; err != nil {
+			return err
+		}
+
+		if key != "" {
+			var prev models.PointsEntry
+			res := tx.Where("driver_id = ? AND idempotency_key = ?", id, key).Limit(1).Find(&prev)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected > 0 {
+				if prev.Delta != req.Delta || prev.ViolationID != req.ViolationID {
+					return ErrIdempotencyMismatch
+				}
+				replayed = true
+				out.NumberOfViolationPoints, out.IsSuspended = prev.PointsAfter, prev.SuspendedAfter
+				return tx.First(&out.Owner, "id = ?", out.OwnerID).Error
+			}
+		}
+
+		date, err := entryDate(tx, id, req.ViolationID, req.Delta, req.Date)
+		if err != nil {
+			return err
+		}
+		entry := models.PointsEntry{
+			DriverID:       id,
+			ViolationID:    req.ViolationID,
+			IdempotencyKey: key,
+			Delta:          req.Delta,
+			Date:           date,
+		}
+		if err := tx.Create(&entry).Error; err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if out.NumberOfViolationPoints, err = s.activePoints(tx, id, now); err != nil {
+			return err
+		}
+		if err := tx.Model(out).Select("NumberOfViolationPoints").Updates(out).Error; err != nil {
+			return err
+		}
+		if err := syncPointsSuspension(tx, out, now); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&entry).Updates(map[string]any{
+			"points_after":    out.NumberOfViolationPoints,
+			"suspended_after": out.IsSuspended,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.First(&out.Owner, "id = ?", out.OwnerID).Error
+	})
+	return replayed, err
+}
+
+// ListPointsEntries vraca istoriju promena poena vozaca, najnovije prvo, sa
+// rokom zastarelosti svake stavke.
+func (s *Store) ListPointsEntries(driverID string, out *[]models.PointsEntry) error {
+	if err := s.DB.Where("driver_id = ?", driverID).Order("date desc, id desc").Find(out).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range *out {
+		e := &(*out)[i]
+		e.ExpiresAt = e.Date.Add(s.PointsExpiry)
+		e.Expired = !e.ExpiresAt.After(now)
+	}
+	return nil
+}
+
+// RecomputePoints preracunava poene svih vozaca iz nezastarelih stavki.
+// Vraca broj vozaca kojima se zbir promenio.
+func (s *Store) RecomputePoints(now time.Time) (int64, error) {
+	res := s.DB.Exec(`
+WITH totals AS (
+	SELECT d.id, GREATEST(COALESCE(SUM(e.delta) FILTER (WHERE e.date > ?), 0), 0) AS points
+	FROM mup_driver_ids d
+	LEFT JOIN mup_points_entries e ON e.driver_id = d.id
+	GROUP BY d.id
+)
+UPDATE mup_driver_ids d SET number_of_violation_points = t.points, updated_at = ?
+FROM totals t
+WHERE t.id = d.id AND d.number_of_violation_points IS DISTINCT FROM t.points`,
+		now.Add(-s.PointsExpiry), now)
+	return res.RowsAffected, res.Error
+}
+
+// RunPointsExpiryJob poziva RecomputePoints odmah i zatim na svakih every,
+// dok se ctx ne otkaze. Posle preracuna ukida suspenzije po poenima vozacima
+// koji su pali ispod praga.
+func (s *Store) RunPointsExpiryJob(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		if n, err := s.RecomputePoints(time.Now()); err != nil {
+			log.Printf("points expiry job: %v", err)
+		} else if n > 0 {
+			log.Printf("points expiry job: %d driver(s) updated", n)
+		}
+		if n, err := s.RefreshSuspensions(time.Now()); err != nil {
+			log.Printf("points expiry job: %v", err)
+		} else if n > 0 {
+			log.Printf("points expiry job: %d driver(s) reinstated or suspended", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
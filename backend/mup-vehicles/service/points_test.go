@@ -0,0 +1,117 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"common/dbtest"
+	"mup-vehicles/data"
+	"mup-vehicles/models"
+)
+
+// newPointsStore pravi store sa vozacem D1 vlasnika O1.
+func newPointsStore(t *testing.T) *Store {
+	t.Helper()
+	db := dbtest.Open(t, data.TablePrefix, &models.Owner{}, &models.DriverId{}, &models.PointsEntry{}, &models.Suspension{})
+	dbtest.Insert(t, db,
+		&models.Owner{ID: "O1", JMBG: "0101990710001", Email: "o1@mup.test"},
+		&models.DriverId{ID: "D1", OwnerID: "O1"},
+	)
+	return NewStore(db)
+}
+
+// addPoints menja poene vozaca D1 sa kljucem izvedenim iz prekrsaja.
+func addPoints(t *testing.T, s *Store, req models.PointsUpdateRequest) (models.DriverId, bool, error) {
+	t.Helper()
+	var d models.DriverId
+	replayed, err := s.AddDriverPoints("D1", req, PointsKey("", req.ViolationID, req.Delta), &d)
+	return d, replayed, err
+}
+
+func TestAddDriverPointsIsIdempotent(t *testing.T) {
+	s := newPointsStore(t)
+	req := models.PointsUpdateRequest{Delta: 3, ViolationID: "V1"}
+
+	if d, replayed, err := addPoints(t, s, req); err != nil || replayed || d.NumberOfViolationPoints != 3 {
+		t.Fatalf("first update: points = %d, replayed = %v, %v", d.NumberOfViolationPoints, replayed, err)
+	}
+	// ponovljen zahtev vraca sacuvano stanje i ne menja zbir
+	if d, replayed, err := addPoints(t, s, req); err != nil || !replayed || d.NumberOfViolationPoints != 3 || d.Owner.ID != "O1" {
+		t.Fatalf("replay: points = %d, replayed = %v, owner = %q, %v", d.NumberOfViolationPoints, replayed, d.Owner.ID, err)
+	}
+
+	// isti kljuc za drugaciju promenu
+	var d models.DriverId
+	other := models.PointsUpdateRequest{Delta: 5, ViolationID: "V1"}
+	if _, err := s.AddDriverPoints("D1", other, PointsKey("", "V1", 3), &d); !errors.Is(err, ErrIdempotencyMismatch) {
+		t.Fatalf("reused key err = %v, want ErrIdempotencyMismatch", err)
+	}
+
+	// vracanje poena za isti prekrsaj je posebna promena
+	if d, replayed, err := addPoints(t, s, models.PointsUpdateRequest{Delta: -3, ViolationID: "V1"}); err != nil || replayed || d.NumberOfViolationPoints != 0 {
+		t.Fatalf("reversal: points = %d, replayed = %v, %v", d.NumberOfViolationPoints, replayed, err)
+	}
+
+	var n int64
+	if err := s.DB.Model(&models.PointsEntry{}).Where("driver_id = ?", "D1").Count(&n).Error; err != nil || n != 2 {
+		t.Fatalf("entries = %d, %v; want 2", n, err)
+	}
+}
+
+func TestPointsLedgerExpiry(t *testing.T) {
+	s := newPointsStore(t)
+
+	// zastarela stavka se upisuje ali ne ulazi u zbir
+	old := time.Now().Add(-s.PointsExpiry - 24*time.Hour)
+	if d, _, err := addPoints(t, s, models.PointsUpdateRequest{Delta: 8, ViolationID: "V0", Date: &old}); err != nil || d.NumberOfViolationPoints != 0 {
+		t.Fatalf("expired entry: points = %d, %v; want 0", d.NumberOfViolationPoints, err)
+	}
+	v1 := time.Now().Add(-30 * 24 * time.Hour)
+	if d, _, err := addPoints(t, s, models.PointsUpdateRequest{Delta: 4, ViolationID: "V1", Date: &v1}); err != nil || d.NumberOfViolationPoints != 4 {
+		t.Fatalf("V1: points = %d, %v; want 4", d.NumberOfViolationPoints, err)
+	}
+
+	// vracanje poena nasledjuje dan prekrsaja, da bi obe stavke zastarele zajedno
+	if d, _, err := addPoints(t, s, models.PointsUpdateRequest{Delta: -4, ViolationID: "V1"}); err != nil || d.NumberOfViolationPoints != 0 {
+		t.Fatalf("V1 reversal: points = %d, %v; want 0", d.NumberOfViolationPoints, err)
+	}
+	var rev models.PointsEntry
+	if err := s.DB.First(&rev, "violation_id = ? AND delta < 0", "V1").Error; err != nil {
+		t.Fatal(err)
+	}
+	if !rev.Date.Equal(v1) {
+		t.Fatalf("reversal date = %v, want %v", rev.Date, v1)
+	}
+
+	var history []models.PointsEntry
+	if err := s.ListPointsEntries("D1", &history); err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 3 {
+		t.Fatalf("history has %d entries, want 3", len(history))
+	}
+	for _, e := range history {
+		if e.Expired != (e.ViolationID == "V0") || !e.ExpiresAt.Equal(e.Date.Add(s.PointsExpiry)) {
+			t.Errorf("entry %s: expired = %v, expiresAt = %v", e.ViolationID, e.Expired, e.ExpiresAt)
+		}
+	}
+}
+
+func TestPointsKey(t *testing.T) {
+	tests := []struct {
+		key, violationID string
+		delta            int
+		want             string
+	}{
+		{"k1", "V1", 2, "k1"},
+		{"", "V1", 2, "violation:V1"},
+		{"", "V1", -2, "violation:V1:reverse"},
+		{"", "", 2, ""},
+	}
+	for _, tt := range tests {
+		if got := PointsKey(tt.key, tt.violationID, tt.delta); got != tt.want {
+			t.Errorf("PointsKey(%q, %q, %d) = %q, want %q", tt.key, tt.violationID, tt.delta, got, tt.want)
+		}
+	}
+}
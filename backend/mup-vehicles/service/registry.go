@@ -0,0 +1,134 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"mup-vehicles/models"
+
+	"gorm.io/gorm"
+)
+
+// RegistryOverlap je koliko se pre kursora ponovo salju izmene. Transakcija
+// koja je pocela pre citanja, a commit-ovana posle, ima updated_at manji od
+// kursora; preklapanje je hvata u sledecem zahtevu (primalac upisuje
+// idempotentno).
+const RegistryOverlap = time.Minute
+
+// Velicina stranice izmena registra (zapisa svih tabela zajedno).
+const (
+	RegistryPageSize    = 500
+	RegistryMaxPageSize = 5000
+)
+
+var ErrRegistryPage = errors.New("invalid registry page token")
+
+// registryTables su tabele registra redom kojim se salju: vlasnici prvi jer
+// ih vozila i vozaci referenciraju.
+var registryTables = []string{"mup_owners", "mup_vehicles", "mup_driver_ids"}
+
+// registryPage je pozicija u izmenama od From: tabela i poslednji poslat
+// zapis (updated_at, id). Start je vreme prve stranice i postaje kursor.
+type registryPage struct {
+	Full  bool      `json:"full,omitempty"`
+	Start time.Time `json:"start"`
+	From  time.Time `json:"from"`
+	Table int       `json:"table"`
+	After time.Time `json:"after"`
+	ID    string    `json:"id,omitempty"`
+}
+
+func (p registryPage) token() string {
+	b, _ := json.Marshal(p)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func parseRegistryPage(token string) (registryPage, error) {
+	var p registryPage
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil || json.Unmarshal(b, &p) != nil ||
+		p.Table < 0 || p.Table >= len(registryTables) {
+		return p, ErrRegistryPage
+	}
+	return p, nil
+}
+
+// RegistryChanges puni out jednom stranicom vlasnika, vozila i vozaca
+// izmenjenih od since (ceo registar kad je since nula), najvise limit zapisa
+// po updated_at i id. Sledeca stranica se trazi sa out.Next; since se tada
+// ne gleda. Cursor je vreme prve stranice.
+func (s *Store) RegistryChanges(since time.Time, token string, limit int, out *models.RegistryChanges) error {
+	if limit < 1 || limit > RegistryMaxPageSize {
+		limit = RegistryPageSize
+	}
+	p := registryPage{Full: since.IsZero(), Start: time.Now()}
+	if !p.Full {
+		p.From = since.Add(-RegistryOverlap)
+		p.After = p.From
+	}
+	if token != "" {
+		var err error
+		if p, err = parseRegistryPage(token); err != nil {
+			return err
+		}
+	}
+
+	*out = models.RegistryChanges{
+		Full:     p.Full,
+		Cursor:   p.Start,
+		Owners:   []models.Owner{},
+		Vehicles: []models.Vehicle{},
+		Drivers:  []models.DriverId{},
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		for ; p.Table < len(registryTables); p.Table, p.After, p.ID = p.Table+1, p.From, "" {
+			table := registryTables[p.Table]
+			q := tx.Order(table + ".updated_at, " + table + ".id").Limit(limit)
+			if !p.After.IsZero() {
+				q = q.Where("("+table+".updated_at, "+table+".id) > (?, ?)", p.After, p.ID)
+			}
+
+			var n int
+			switch p.Table {
+			case 0:
+				var list []models.Owner
+				if err := q.Find(&list).Error; err != nil {
+					return err
+				}
+				if n = len(list); n > 0 {
+					p.After, p.ID = list[n-1].UpdatedAt, list[n-1].ID
+				}
+				out.Owners = append(out.Owners, list...)
+			case 1:
+				var list []models.Vehicle
+				if err := q.Find(&list).Error; err != nil {
+					return err
+				}
+				if n = len(list); n > 0 {
+					p.After, p.ID = list[n-1].UpdatedAt, list[n-1].ID
+				}
+				out.Vehicles = append(out.Vehicles, list...)
+			default:
+				var list []models.DriverId
+				if err := withSuspended(q, p.Start).Find(&list).Error; err != nil {
+					return err
+				}
+				if n = len(list); n > 0 {
+					p.After, p.ID = list[n-1].UpdatedAt, list[n-1].ID
+				}
+				out.Drivers = append(out.Drivers, list...)
+			}
+
+			// puna stranica: ostatak tabele (ako ga ima) ide na sledecoj
+			if limit -= n; limit == 0 {
+				out.Next = p.token()
+				return nil
+			}
+		}
+		return nil
+	}, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+}
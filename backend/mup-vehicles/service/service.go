@@ -0,0 +1,166 @@
+package service
+
+import (
+	"errors"
+	"mup-vehicles/models"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// SuspensionThreshold je broj kaznenih poena od kog se vozacka suspenduje.
+const SuspensionThreshold = 10
+
+type Store struct {
+	DB *gorm.DB
+	// PointsExpiry je rok posle kog stavka poena vise ne ulazi u zbir.
+	PointsExpiry time.Duration
+}
+
+func NewStore(db *gorm.DB) *Store {
+	return &Store{DB: db, PointsExpiry: DefaultPointsExpiry}
+}
+
+//
+// ===== Vehicles =====
+//
+
+func (s *Store) ListVehicles(out *[]models.Vehicle) error {
+	return s.DB.Preload("Owner").Order("id").Find(out).Error
+}
+
+func (s *Store) GetVehicleByRegistration(registration string, out *models.Vehicle) error {
+	return s.DB.Preload("Owner").First(out, "registration = ?", registration).Error
+}
+
+func (s *Store) GetVehicleByOwnerJMBG(jmbg string, out *models.Vehicle) error {
+	return s.DB.Preload("Owner").
+		Joins("JOIN mup_owners o ON o.id = mup_vehicles.owner_id").
+		Where("o.jmbg = ?", jmbg).
+		Order("mup_vehicles.id").
+		First(out).Error
+}
+
+// ListVehiclesByOwnerJMBG vraca sva vozila vlasnika sa datim JMBG-om.
+func (s *Store) ListVehiclesByOwnerJMBG(jmbg string, out *[]models.Vehicle) error {
+	return s.DB.Preload("Owner").
+		Joins("JOIN mup_owners o ON o.id = mup_vehicles.owner_id").
+		Where("o.jmbg = ?", jmbg).
+		Order("mup_vehicles.id").
+		Find(out).Error
+}
+
+//
+// ===== Drivers =====
+//
+
+func (s *Store) ListDrivers(out *[]models.DriverId) error {
+	return withSuspended(s.DB, time.Now()).Preload("Owner").Order("id").Find(out).Error
+}
+
+func (s *Store) GetDriver(id string, out *models.DriverId) error {
+	return withSuspended(s.DB, time.Now()).Preload("Owner").First(out, "id = ?", id).Error
+}
+
+func (s *Store) GetDriverByOwnerEmail(email string, out *models.DriverId) error {
+	return withSuspended(s.DB, time.Now()).Preload("Owner").
+		Joins("JOIN mup_owners o ON o.id = mup_driver_ids.owner_id").
+		Where("LOWER(o.email) = ?", normalizeEmail(email)).
+		Order("mup_driver_ids.id").
+		First(out).Error
+}
+
+//
+// ===== Owners =====
+//
+
+func (s *Store) ListOwners(out *[]models.Owner) error {
+	return s.DB.Order("id").Find(out).Error
+}
+
+func (s *Store) GetOwnerByEmail(email string, out *models.Owner) error {
+	return s.DB.First(out, "LOWER(email) = ?", normalizeEmail(email)).Error
+}
+
+//
+// ===== Admins =====
+//
+
+func (s *Store) ListAdmins(out *[]models.Administrator) error {
+	return s.DB.Order("id").Find(out).Error
+}
+
+func (s *Store) GetAdminByEmail(email string, out *models.Administrator) error {
+	return s.DB.First(out, "email = ?", email).Error
+}
+
+//
+// ===== Credentials =====
+//
+
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// dummyHash se poredi kad nalog ne postoji, da vreme odgovora ne bi
+// otkrivalo koji email je registrovan.
+var dummyHash, _ = bcrypt.GenerateFromPassword([]byte("dummy-password"), bcrypt.DefaultCost)
+
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// VerifyCredentials proverava email (tacno poklapanje) i lozinku vlasnika
+// ili administratora. Za nepostojeci nalog i pogresnu lozinku vraca isti
+// ErrInvalidCredentials.
+func (s *Store) VerifyCredentials(email, password string, out *models.LoginResponse) error {
+	email = normalizeEmail(email)
+
+	var o models.Owner
+	res := s.DB.Where("LOWER(email) = ?", email).Limit(1).Find(&o)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		if bcrypt.CompareHashAndPassword([]byte(o.Password), []byte(password)) != nil {
+			return ErrInvalidCredentials
+		}
+
+		var d models.DriverId
+		dres := s.DB.Where("owner_id = ?", o.ID).Order("id").Limit(1).Find(&d)
+		if dres.Error != nil {
+			return dres.Error
+		}
+		*out = models.LoginResponse{
+			Kind:      models.KindOwner,
+			ID:        o.ID,
+			DriverID:  d.ID,
+			JMBG:      o.JMBG,
+			Email:     o.Email,
+			FirstName: o.FirstName,
+			LastName:  o.LastName,
+		}
+		return nil
+	}
+
+	var a models.Administrator
+	res = s.DB.Where("LOWER(email) = ?", email).Limit(1).Find(&a)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return ErrInvalidCredentials
+	}
+	if bcrypt.CompareHashAndPassword([]byte(a.Password), []byte(password)) != nil {
+		return ErrInvalidCredentials
+	}
+	*out = models.LoginResponse{
+		Kind:      models.KindAdmin,
+		ID:        a.ID,
+		Email:     a.Email,
+		FirstName: a.FirstName,
+		LastName:  a.LastName,
+	}
+	return nil
+}
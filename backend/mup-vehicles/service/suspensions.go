@@ -0,0 +1,216 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"mup-vehicles/models"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrSuspensionReason = errors.New("suspension reason is required")
+	ErrSuspensionTerm   = errors.New("suspension must end after it starts")
+)
+
+// activeSuspensions ogranicava upit na suspenzije koje vaze u trenutku now.
+func activeSuspensions(q *gorm.DB, now time.Time) *gorm.DB {
+	return q.Where("lifted_at IS NULL AND starts_at <= ? AND (ends_at IS NULL OR ends_at > ?)", now, now)
+}
+
+// withSuspended racuna is_suspended pri citanju vozaca iz suspenzija koje
+// vaze u trenutku now, pa istekla ili tek zapoceta suspenzija ne ceka
+// RefreshSuspensions.
+func withSuspended(q *gorm.DB, now time.Time) *gorm.DB {
+	return q.Select(`mup_driver_ids.id, mup_driver_ids.number_of_violation_points,
+	mup_driver_ids.picture, mup_driver_ids.owner_id,
+	mup_driver_ids.created_at, mup_driver_ids.updated_at, EXISTS (
+		SELECT 1 FROM mup_suspensions s
+		WHERE s.driver_id = mup_driver_ids.id AND s.lifted_at IS NULL
+			AND s.starts_at <= ? AND (s.ends_at IS NULL OR s.ends_at > ?)
+	) AS is_suspended`, now, now)
+}
+
+// refreshSuspended uskladjuje IsSuspended zakljucanog vozaca sa zapisima.
+func refreshSuspended(tx *gorm.DB, d *models.DriverId, now time.Time) error {
+	var n int64
+	if err := activeSuspensions(tx.Model(&models.Suspension{}), now).
+		Where("driver_id = ?", d.ID).Count(&n).Error; err != nil {
+		return err
+	}
+	if d.IsSuspended == (n > 0) {
+		return nil
+	}
+	d.IsSuspended = n > 0
+	return tx.Model(d).Select("IsSuspended").Updates(d).Error
+}
+
+// syncPointsSuspension izrice suspenziju kad poeni dostignu prag, a ukida je
+// kad padnu ispod njega (zastarelost, vraceni poeni).
+func syncPointsSuspension(tx *gorm.DB, d *models.DriverId, now time.Time) error {
+	var active []models.Suspension
+	if err := activeSuspensions(tx, now).
+		Where("driver_id = ? AND source = ?", d.ID, models.SuspensionPoints).
+		Find(&active).Error; err != nil {
+		return err
+	}
+
+	switch {
+	case d.NumberOfViolationPoints >= SuspensionThreshold && len(active) == 0:
+		if err := tx.Create(&models.Suspension{
+			DriverID:  d.ID,
+			Source:    models.SuspensionPoints,
+			Reason:    fmt.Sprintf("%d violation points (threshold %d)", d.NumberOfViolationPoints, SuspensionThreshold),
+			Authority: models.AuthoritySystem,
+			StartsAt:  now,
+		}).Error; err != nil {
+			return err
+		}
+	case d.NumberOfViolationPoints < SuspensionThreshold && len(active) > 0:
+		if err := tx.Model(&models.Suspension{}).
+			Where("id IN ?", suspensionIDs(active)).
+			Updates(map[string]any{"lifted_at": now, "lifted_by": models.AuthoritySystem}).Error; err != nil {
+			return err
+		}
+	}
+	return refreshSuspended(tx, d, now)
+}
+
+func suspensionIDs(list []models.Suspension) []uint {
+	ids := make([]uint, len(list))
+	for i, s := range list {
+		ids[i] = s.ID
+	}
+	return ids
+}
+
+// Suspend izrice suspenziju vozacke. Bez StartsAt pocinje odmah, bez EndsAt
+// traje do ukidanja.
+func (s *Store) Suspend(driverID string, req models.SuspendRequest, authority, issuedBy string, out *models.DriverId) error {
+	if strings.TrimSpace(req.Reason) == "" {
+		return ErrSuspensionReason
+	}
+	now := time.Now()
+	start := now
+	if req.StartsAt != nil && !req.StartsAt.IsZero() {
+		start = *req.StartsAt
+	}
+	if req.EndsAt != nil && !req.EndsAt.After(start) {
+		return ErrSuspensionTerm
+	}
+
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(out, "id = ?", driverID).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.Suspension{
+			DriverID:  driverID,
+			Source:    models.SuspensionManual,
+			Reason:    req.Reason,
+			Authority: authority,
+			IssuedBy:  issuedBy,
+			StartsAt:  start,
+			EndsAt:    req.EndsAt,
+		}).Error; err != nil {
+			return err
+		}
+		if err := refreshSuspended(tx, out, now); err != nil {
+			return err
+		}
+		return tx.First(&out.Owner, "id = ?", out.OwnerID).Error
+	})
+}
+
+// LiftSuspensions ukida sve aktivne i buduce suspenzije vozaca. Ako poeni i
+// dalje prelaze prag, suspenzija po poenima se izrice ponovo pri sledecoj
+// promeni poena.
+func (s *Store) LiftSuspensions(driverID, by string, out *models.DriverId) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(out, "id = ?", driverID).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := tx.Model(&models.Suspension{}).
+			Where("driver_id = ? AND lifted_at IS NULL AND (ends_at IS NULL OR ends_at > ?)", driverID, now).
+			Updates(map[string]any{"lifted_at": now, "lifted_by": by}).Error; err != nil {
+			return err
+		}
+		if err := refreshSuspended(tx, out, now); err != nil {
+			return err
+		}
+		return tx.First(&out.Owner, "id = ?", out.OwnerID).Error
+	})
+}
+
+// ListSuspensions vraca istoriju suspenzija vozaca, najnovije prvo.
+func (s *Store) ListSuspensions(driverID string, out *[]models.Suspension) error {
+	if err := s.DB.Where("driver_id = ?", driverID).Order("starts_at desc, id desc").Find(out).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range *out {
+		(*out)[i].Active = (*out)[i].ActiveAt(now)
+	}
+	return nil
+}
+
+// RefreshSuspensions ukida suspenzije po poenima vozacima ispod praga i
+// uskladjuje sacuvani IsSuspended svih vozaca sa zapisima. Citanja ga racunaju
+// sama (withSuspended); ovo pomera updated_at, pa promenu vidi i sinhronizacija
+// registra. Vraca broj vozaca kojima se promenio status.
+func (s *Store) RefreshSuspensions(now time.Time) (int64, error) {
+	var changed int64
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+UPDATE mup_suspensions s SET lifted_at = ?, lifted_by = ?
+FROM mup_driver_ids d
+WHERE d.id = s.driver_id
+	AND s.source = ? AND s.lifted_at IS NULL
+	AND d.number_of_violation_points < ?`,
+			now, models.AuthoritySystem, models.SuspensionPoints, SuspensionThreshold).Error; err != nil {
+			return err
+		}
+
+		res := tx.Exec(`
+WITH status AS (
+	SELECT d.id, EXISTS (
+		SELECT 1 FROM mup_suspensions s
+		WHERE s.driver_id = d.id AND s.lifted_at IS NULL
+			AND s.starts_at <= ? AND (s.ends_at IS NULL OR s.ends_at > ?)
+	) AS suspended
+	FROM mup_driver_ids d
+)
+UPDATE mup_driver_ids d SET is_suspended = st.suspended, updated_at = ?
+FROM status st
+WHERE st.id = d.id AND d.is_suspended IS DISTINCT FROM st.suspended`, now, now, now)
+		changed = res.RowsAffected
+		return res.Error
+	})
+	return changed, err
+}
+
+// RunSuspensionJob periodicno poziva RefreshSuspensions dok se ctx ne otkaze.
+func (s *Store) RunSuspensionJob(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		if n, err := s.RefreshSuspensions(time.Now()); err != nil {
+			log.Printf("suspension job: %v", err)
+		} else if n > 0 {
+			log.Printf("suspension job: %d driver(s) changed status", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
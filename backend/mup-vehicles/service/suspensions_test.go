@@ -0,0 +1,82 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"common/dbtest"
+	"mup-vehicles/models"
+)
+
+func TestPointsSuspensionThreshold(t *testing.T) {
+	s := newPointsStore(t)
+	active := func() int64 {
+		t.Helper()
+		var n int64
+		if err := activeSuspensions(s.DB.Model(&models.Suspension{}), time.Now()).
+			Where("driver_id = ? AND source = ?", "D1", models.SuspensionPoints).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if d, _, err := addPoints(t, s, models.PointsUpdateRequest{Delta: SuspensionThreshold - 1, ViolationID: "V1"}); err != nil || d.IsSuspended {
+		t.Fatalf("below threshold: suspended = %v, %v", d.IsSuspended, err)
+	}
+	if d, _, err := addPoints(t, s, models.PointsUpdateRequest{Delta: 1, ViolationID: "V2"}); err != nil || !d.IsSuspended {
+		t.Fatalf("at threshold: suspended = %v, %v", d.IsSuspended, err)
+	}
+	if n := active(); n != 1 {
+		t.Fatalf("active points suspensions = %d, want 1", n)
+	}
+
+	// pad ispod praga ukida suspenziju po poenima
+	if d, _, err := addPoints(t, s, models.PointsUpdateRequest{Delta: -1, ViolationID: "V2"}); err != nil || d.IsSuspended {
+		t.Fatalf("after reversal: suspended = %v, %v", d.IsSuspended, err)
+	}
+	if n := active(); n != 0 {
+		t.Fatalf("active points suspensions = %d, want 0", n)
+	}
+}
+
+func TestDriverSuspendedIsComputedOnRead(t *testing.T) {
+	s := newPointsStore(t)
+	now := time.Now()
+	// sacuvana kolona kasni za zapisima dok je posao ne osvezi
+	if err := s.DB.Model(&models.DriverId{}).Where("id = ?", "D1").Update("is_suspended", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	suspended := func() bool {
+		t.Helper()
+		var d models.DriverId
+		if err := s.GetDriver("D1", &d); err != nil {
+			t.Fatal(err)
+		}
+		return d.IsSuspended
+	}
+
+	ended := now.Add(-time.Hour)
+	dbtest.Insert(t, s.DB,
+		&models.Suspension{DriverID: "D1", Source: models.SuspensionManual, Reason: "istekla", StartsAt: now.Add(-48 * time.Hour), EndsAt: &ended},
+		&models.Suspension{DriverID: "D1", Source: models.SuspensionManual, Reason: "buduca", StartsAt: now.Add(time.Hour)},
+	)
+	if suspended() {
+		t.Fatal("expired and future suspensions count as active")
+	}
+
+	var d models.DriverId
+	until := now.Add(time.Hour)
+	if err := s.Suspend("D1", models.SuspendRequest{IsSuspended: true, Reason: "odluka suda", EndsAt: &until}, "MUP", "m1", &d); err != nil {
+		t.Fatal(err)
+	}
+	if !d.IsSuspended || !suspended() {
+		t.Fatal("active suspension is not reported")
+	}
+
+	if err := s.LiftSuspensions("D1", "m1", &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.IsSuspended || suspended() {
+		t.Fatal("lifted suspension is still reported")
+	}
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"mup-vehicles/models"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrTransferInvalid = errors.New("invalid transfer")
+	ErrVehicleStolen   = errors.New("vehicle is reported stolen")
+	ErrNotOwner        = errors.New("seller is not the current owner of the vehicle")
+	ErrOwnerChanged    = errors.New("vehicle owner changed since the transfer was requested")
+	ErrTransferOpen    = errors.New("vehicle already has a pending transfer")
+	ErrTransferClosed  = errors.New("transfer is no longer pending")
+	ErrNotParty        = errors.New("only the seller or the buyer can confirm the transfer")
+)
+
+func preloadTransfer(q *gorm.DB) *gorm.DB {
+	return q.Preload("Vehicle").Preload("Vehicle.Owner").Preload("OldOwner").Preload("NewOwner")
+}
+
+// transfersNewestFirst: prenosi na cekanju (bez datuma) po vremenu zahteva.
+func transfersNewestFirst(q *gorm.DB) *gorm.DB {
+	return q.Order("COALESCE(date_of_transfer, mup_ownership_transfers.created_at) DESC")
+}
+
+func (s *Store) ListTransfers(status models.TransferStatus, out *[]models.OwnershipTransfer) error {
+	q := preloadTransfer(s.DB)
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	return transfersNewestFirst(q).Find(out).Error
+}
+
+func (s *Store) GetTransfer(id string, out *models.OwnershipTransfer) error {
+	return preloadTransfer(s.DB).First(out, "id = ?", id).Error
+}
+
+// ListVehicleTransfers je istorija prenosa vozila sa datim tablicama.
+func (s *Store) ListVehicleTransfers(registration string, out *[]models.OwnershipTransfer) error {
+	q := preloadTransfer(s.DB).
+		Joins("JOIN mup_vehicles v ON v.id = mup_ownership_transfers.vehicle_id").
+		Where("v.registration = ?", registration)
+	return transfersNewestFirst(q).Find(out).Error
+}
+
+// ListOwnerTransfers vraca prenose u kojima je vlasnik prodavac ili kupac.
+func (s *Store) ListOwnerTransfers(ownerID string, out *[]models.OwnershipTransfer) error {
+	q := preloadTransfer(s.DB).Where("old_owner_id = ? OR new_owner_id = ?", ownerID, ownerID)
+	return transfersNewestFirst(q).Find(out).Error
+}
+
+// RequestTransfer otvara prenos vozila sa prodavca sellerID na vlasnika sa
+// req.BuyerJMBG. Zahtev prodavca je ujedno i njegova potvrda.
+func (s *Store) RequestTransfer(req models.TransferRequest, sellerID string, out *models.OwnershipTransfer) error {
+	reg := strings.ToUpper(strings.TrimSpace(req.Registration))
+	jmbg := strings.TrimSpace(req.BuyerJMBG)
+	if reg == "" || jmbg == "" {
+		return fmt.Errorf("%w: registration and buyerJmbg are required", ErrTransferInvalid)
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var v models.Vehicle
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&v, "registration = ?", reg).Error; err != nil {
+			return err
+		}
+		if v.IsStolen {
+			return ErrVehicleStolen
+		}
+		if sellerID == "" || v.OwnerID != sellerID {
+			return ErrNotOwner
+		}
+
+		var buyer models.Owner
+		res := tx.Where("jmbg = ?", jmbg).Limit(1).Find(&buyer)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return fmt.Errorf("%w: buyer not found", ErrTransferInvalid)
+		}
+		if buyer.ID == sellerID {
+			return fmt.Errorf("%w: buyer is already the owner", ErrTransferInvalid)
+		}
+
+		var seq int64
+		if err := tx.Raw("SELECT nextval('mup_ownership_transfer_seq')").Scan(&seq).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		*out = models.OwnershipTransfer{
+			ID:                fmt.Sprintf("TRA-%d", seq),
+			VehicleID:         v.ID,
+			OldOwnerID:        sellerID,
+			NewOwnerID:        buyer.ID,
+			Status:            models.TransferPending,
+			SellerConfirmedAt: &now,
+		}
+		return tx.Omit(clause.Associations).Create(out).Error
+	})
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return ErrTransferOpen
+	}
+	if err != nil {
+		return err
+	}
+	return s.GetTransfer(out.ID, out)
+}
+
+// ConfirmTransfer belezi potvrdu prodavca ili kupca (ownerID). Kad su obe
+// potvrde tu, u istoj transakciji se ponovo proverava vozilo i menja mu se
+// vlasnik.
+func (s *Store) ConfirmTransfer(id, ownerID string, out *models.OwnershipTransfer) error {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var t models.OwnershipTransfer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&t, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if t.Status != models.TransferPending {
+			return ErrTransferClosed
+		}
+
+		now := time.Now()
+		switch ownerID {
+		case "":
+			return ErrNotParty
+		case t.OldOwnerID:
+			if t.SellerConfirmedAt == nil {
+				t.SellerConfirmedAt = &now
+			}
+		case t.NewOwnerID:
+			if t.BuyerConfirmedAt == nil {
+				t.BuyerConfirmedAt = &now
+			}
+		default:
+			return ErrNotParty
+		}
+
+		if t.SellerConfirmedAt != nil && t.BuyerConfirmedAt != nil {
+			var v models.Vehicle
+			if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				First(&v, "id = ?", t.VehicleID).Error; err != nil {
+				return err
+			}
+			if v.IsStolen {
+				return ErrVehicleStolen
+			}
+			if v.OwnerID != t.OldOwnerID {
+				return ErrOwnerChanged
+			}
+			v.OwnerID = t.NewOwnerID
+			if err := tx.Model(&v).Select("OwnerID").Updates(&v).Error; err != nil {
+				return err
+			}
+			t.Status, t.DateOfTransfer = models.TransferCompleted, &now
+		}
+		return tx.Model(&t).
+			Select("SellerConfirmedAt", "BuyerConfirmedAt", "Status", "DateOfTransfer").
+			Updates(&t).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.GetTransfer(id, out)
+}
+
+// CancelTransfer odustaje od prenosa na cekanju (prodavac, kupac ili MUP).
+func (s *Store) CancelTransfer(id, by, reason string, out *models.OwnershipTransfer) error {
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var t models.OwnershipTransfer
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(&t, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if t.Status != models.TransferPending {
+			return ErrTransferClosed
+		}
+
+		now := time.Now()
+		t.Status, t.CancelledAt, t.CancelledBy = models.TransferCancelled, &now, by
+		t.CancelReason = strings.TrimSpace(reason)
+		return tx.Model(&t).
+			Select("Status", "CancelledAt", "CancelledBy", "CancelReason").
+			Updates(&t).Error
+	})
+	if err != nil {
+		return err
+	}
+	return s.GetTransfer(id, out)
+}
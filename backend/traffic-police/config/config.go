@@ -15,7 +15,22 @@ type Config struct {
 	DBPass       string
 	DBName       string
 	MupBaseURL   string
-	MupTimeoutMs int
+	MupTimeoutMs int // po pokusaju
+	// MupRetryAttempts je broj pokusaja GET zahteva ka MUP-u, MupCacheTTLMs
+	// koliko se kesiraju vozila i vozaci (0 iskljucuje kes).
+	MupRetryAttempts int
+	MupCacheTTLMs    int
+	// RegistrySyncMinutes je period sinhronizacije kopije MUP registra (0 je iskljucuje).
+	RegistrySyncMinutes int
+	Issuer              string
+	// JWKSURL je adresa javnih kljuceva auth servisa.
+	JWKSURL string
+	// AuthURL/ServiceSecret sluze za dobijanje servisnog tokena (POST /token).
+	AuthURL       string
+	ServiceSecret string
+	// Primalac i racun za uplatu kazni (nalog za uplatu).
+	FineRecipient string
+	FineAccount   string
 }
 
 func GetConfig() Config {
@@ -29,6 +44,11 @@ func GetConfig() Config {
 		mup = "http://mup-vehicles-service:8081"
 	}
 
+	authURL := os.Getenv("AUTH_BASE_URL")
+	if authURL == "" {
+		authURL = "http://auth-service:8080"
+	}
+
 	timeoutMs := 3000
 	if v := os.Getenv("MUP_TIMEOUT_MS"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil {
@@ -36,14 +56,56 @@ func GetConfig() Config {
 		}
 	}
 
+	retryAttempts := 3
+	if v := os.Getenv("MUP_RETRY_ATTEMPTS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			retryAttempts = n
+		}
+	}
+
+	cacheTTLMs := 15000
+	if v := os.Getenv("MUP_CACHE_TTL_MS"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			cacheTTLMs = n
+		}
+	}
+
+	syncMinutes := 5
+	if v := os.Getenv("REGISTRY_SYNC_MINUTES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			syncMinutes = n
+		}
+	}
+
+	recipient := os.Getenv("FINE_RECIPIENT")
+	if recipient == "" {
+		recipient = "Budzet Republike Srbije"
+	}
+
+	account := os.Getenv("FINE_ACCOUNT")
+	if account == "" {
+		account = "840-742221843-57"
+	}
+
 	return Config{
-		DBHost:       os.Getenv("DB_HOST"),
-		DBUser:       os.Getenv("DB_USER"),
-		DBPass:       os.Getenv("DB_PASS"),
-		DBName:       os.Getenv("DB_NAME"),
-		ServiceHost:  os.Getenv("SERVICE_HOST"),
-		ServicePort:  port,
-		MupBaseURL:   mup,
-		MupTimeoutMs: timeoutMs,
+		DBHost:        os.Getenv("DB_HOST"),
+		DBUser:        os.Getenv("DB_USER"),
+		DBPass:        os.Getenv("DB_PASS"),
+		DBName:        os.Getenv("DB_NAME"),
+		ServiceHost:   os.Getenv("SERVICE_HOST"),
+		ServicePort:   port,
+		MupBaseURL:    mup,
+		MupTimeoutMs:  timeoutMs,
+		Issuer:        os.Getenv("ISSUER"),
+		JWKSURL:       authURL + "/.well-known/jwks.json",
+		AuthURL:       authURL,
+		ServiceSecret: os.Getenv("SERVICE_SECRET"),
+		FineRecipient: recipient,
+		FineAccount:   account,
+
+		MupRetryAttempts: retryAttempts,
+		MupCacheTTLMs:    cacheTTLMs,
+
+		RegistrySyncMinutes: syncMinutes,
 	}
 }
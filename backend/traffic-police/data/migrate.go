@@ -1,8 +1,8 @@
 package data
 
 import (
+	"common/migrate"
 	"fmt"
-	"traffic-police/models"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
@@ -17,21 +17,7 @@ func InitDB(host, user, password, dbname string, port int) (*gorm.DB, error) {
 	return db, nil
 }
 
-func AutoMigrate(db *gorm.DB) error {
-
-	err := db.AutoMigrate(
-		&models.PoliceProfile{},
-		&models.Owner{},
-		&models.Vehicle{},
-		&models.Violation{},
-		&models.OwnershipTransfer{},
-		&models.Driver{},
-		&models.Fine{},
-		&models.User{},
-	)
-	if err != nil {
-		return err
-	}
-
-	return nil
+// Migrator vraca runner za migracije traffic-police servisa (vidi migrations.go).
+func Migrator(db *gorm.DB) migrate.Runner {
+	return migrate.New(db, "traffic-police", migrations)
 }
@@ -0,0 +1,405 @@
+package data
+
+import "common/migrate"
+
+// Migracije se nikad ne menjaju posle objavljivanja; izmena seme je nova
+// verzija. IF NOT EXISTS omogucava da se baza napravljena ranijim
+// AutoMigrate-om preuzme bez gresaka.
+var migrations = []migrate.Migration{
+	{
+		Version: 1,
+		Name:    "create_registry",
+		Up: `
+CREATE TABLE IF NOT EXISTS owners (
+	id         text PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	first_name text,
+	last_name  text,
+	address    text,
+	jmbg       text,
+	email      text,
+	user_id    text
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_owners_jmbg ON owners (jmbg);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_owners_user_id ON owners (user_id);
+
+CREATE TABLE IF NOT EXISTS drivers (
+	id                          text PRIMARY KEY,
+	created_at                  timestamptz,
+	updated_at                  timestamptz,
+	is_suspended                boolean,
+	number_of_violation_points  bigint,
+	picture                     text,
+	owner_id                    text,
+	CONSTRAINT fk_drivers_owner FOREIGN KEY (owner_id) REFERENCES owners (id)
+);
+CREATE INDEX IF NOT EXISTS idx_drivers_owner_id ON drivers (owner_id);
+
+CREATE TABLE IF NOT EXISTS vehicles (
+	id           text PRIMARY KEY,
+	created_at   timestamptz,
+	updated_at   timestamptz,
+	mark         text,
+	model        text,
+	registration text,
+	year         bigint,
+	color        text,
+	is_stolen    boolean,
+	owner_id     text,
+	CONSTRAINT fk_vehicles_owner FOREIGN KEY (owner_id) REFERENCES owners (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_vehicles_registration ON vehicles (registration);
+CREATE INDEX IF NOT EXISTS idx_vehicles_owner_id ON vehicles (owner_id);
+
+CREATE TABLE IF NOT EXISTS ownership_transfers (
+	id               text PRIMARY KEY,
+	created_at       timestamptz,
+	updated_at       timestamptz,
+	vehicle_id       text,
+	owner_old_id     text,
+	owner_new_id     text,
+	date_of_transfer timestamptz,
+	CONSTRAINT fk_ownership_transfers_vehicle FOREIGN KEY (vehicle_id) REFERENCES vehicles (id),
+	CONSTRAINT fk_ownership_transfers_owner_old FOREIGN KEY (owner_old_id) REFERENCES owners (id),
+	CONSTRAINT fk_ownership_transfers_owner_new FOREIGN KEY (owner_new_id) REFERENCES owners (id)
+);
+CREATE INDEX IF NOT EXISTS idx_ownership_transfers_vehicle_id ON ownership_transfers (vehicle_id);
+CREATE INDEX IF NOT EXISTS idx_ownership_transfers_owner_old_id ON ownership_transfers (owner_old_id);
+CREATE INDEX IF NOT EXISTS idx_ownership_transfers_owner_new_id ON ownership_transfers (owner_new_id);
+`,
+		Down: `
+DROP TABLE IF EXISTS ownership_transfers;
+DROP TABLE IF EXISTS vehicles;
+DROP TABLE IF EXISTS drivers;
+DROP TABLE IF EXISTS owners;
+`,
+	},
+	{
+		Version: 2,
+		Name:    "create_violations_and_fines",
+		Up: `
+CREATE TABLE IF NOT EXISTS violations (
+	id                text PRIMARY KEY,
+	created_at        timestamptz,
+	updated_at        timestamptz,
+	type_of_violation text,
+	date              timestamptz,
+	location          text,
+	driver_id         text,
+	vehicle_id        text,
+	police_id         text
+);
+CREATE INDEX IF NOT EXISTS idx_violations_driver_id ON violations (driver_id);
+CREATE INDEX IF NOT EXISTS idx_violations_vehicle_id ON violations (vehicle_id);
+CREATE INDEX IF NOT EXISTS idx_violations_police_id ON violations (police_id);
+
+CREATE TABLE IF NOT EXISTS fines (
+	id           text PRIMARY KEY,
+	created_at   timestamptz,
+	updated_at   timestamptz,
+	amount       decimal,
+	is_paid      boolean,
+	date         timestamptz,
+	violation_id text
+);
+CREATE INDEX IF NOT EXISTS idx_fines_violation_id ON fines (violation_id);
+`,
+		Down: `
+DROP TABLE IF EXISTS fines;
+DROP TABLE IF EXISTS violations;
+`,
+	},
+	{
+		Version: 3,
+		Name:    "police_columns_on_users",
+		// users tabelu pravi auth (njegova migracija 1); ovde samo za slucaj
+		// da traffic-police krene prvi, pa dodajemo kolone policijskog profila
+		Up: `
+CREATE TABLE IF NOT EXISTS users (
+	id         text PRIMARY KEY,
+	created_at timestamptz,
+	updated_at timestamptz,
+	email      text NOT NULL,
+	password   text NOT NULL,
+	role       text NOT NULL
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS first_name   text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS last_name    text NOT NULL DEFAULT '';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS rank         text;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_suspended boolean NOT NULL DEFAULT false;
+`,
+		Down: `
+ALTER TABLE users DROP COLUMN IF EXISTS is_suspended;
+ALTER TABLE users DROP COLUMN IF EXISTS rank;
+`,
+	},
+	{
+		Version: 4,
+		Name:    "fine_lifecycle",
+		// jedna kazna po prekrsaju; paid_at belezi kad je placena
+		Up: `
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS paid_at timestamptz;
+UPDATE fines SET is_paid = false WHERE is_paid IS NULL;
+ALTER TABLE fines ALTER COLUMN is_paid SET DEFAULT false;
+ALTER TABLE fines ALTER COLUMN is_paid SET NOT NULL;
+DROP INDEX IF EXISTS idx_fines_violation_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fines_violation_id ON fines (violation_id);
+`,
+		Down: `
+DROP INDEX IF EXISTS idx_fines_violation_id;
+CREATE INDEX IF NOT EXISTS idx_fines_violation_id ON fines (violation_id);
+ALTER TABLE fines ALTER COLUMN is_paid DROP NOT NULL;
+ALTER TABLE fines ALTER COLUMN is_paid DROP DEFAULT;
+ALTER TABLE fines DROP COLUMN IF EXISTS paid_at;
+`,
+	},
+	{
+		Version: 5,
+		Name:    "create_tariffs",
+		// tarifa se nikad ne menja unazad: nova verzija je novi red sa
+		// kasnijim effective_from, prekrsaj pamti tarifu po kojoj je izdat
+		Up: `
+CREATE TABLE IF NOT EXISTS tariffs (
+	id                text PRIMARY KEY,
+	created_at        timestamptz,
+	updated_at        timestamptz,
+	type_of_violation text NOT NULL,
+	effective_from    timestamptz NOT NULL,
+	points            bigint NOT NULL,
+	fine_min          decimal NOT NULL,
+	fine_max          decimal NOT NULL,
+	risk_weight       bigint NOT NULL,
+	CHECK (fine_min <= fine_max)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tariffs_type_effective ON tariffs (type_of_violation, effective_from);
+
+INSERT INTO tariffs (id, created_at, updated_at, type_of_violation, effective_from, points, fine_min, fine_max, risk_weight) VALUES
+	('TAR-MINOR-1',    now(), now(), 'MINOR',    '2000-01-01', 1,  3000,  10000,  2),
+	('TAR-MAJOR-1',    now(), now(), 'MAJOR',    '2000-01-01', 3, 10000,  40000,  5),
+	('TAR-CRITICAL-1', now(), now(), 'CRITICAL', '2000-01-01', 5, 30000, 120000, 10)
+ON CONFLICT DO NOTHING;
+
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS tariff_id text;
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS points bigint NOT NULL DEFAULT 0;
+`,
+		Down: `
+ALTER TABLE violations DROP COLUMN IF EXISTS points;
+ALTER TABLE violations DROP COLUMN IF EXISTS tariff_id;
+DROP TABLE IF EXISTS tariffs;
+`,
+	},
+	{
+		Version: 6,
+		Name:    "fine_payment_references",
+		// poziv na broj po modelu 97: dve kontrolne cifre + redni broj iz
+		// sekvence (10 cifara); postojece kazne dobijaju broj odmah
+		Up: `
+CREATE SEQUENCE IF NOT EXISTS fine_payment_ref_seq;
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS payment_reference text;
+UPDATE fines SET payment_reference = lpad((98 - (s.n::numeric * 100) % 97)::text, 2, '0') || lpad(s.n::text, 10, '0')
+FROM (
+	SELECT id, nextval('fine_payment_ref_seq') AS n
+	FROM (SELECT id FROM fines WHERE payment_reference IS NULL ORDER BY created_at, id) f
+) s
+WHERE fines.id = s.id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fines_payment_reference ON fines (payment_reference);
+`,
+		Down: `
+DROP INDEX IF EXISTS idx_fines_payment_reference;
+ALTER TABLE fines DROP COLUMN IF EXISTS payment_reference;
+DROP SEQUENCE IF EXISTS fine_payment_ref_seq;
+`,
+	},
+	{
+		Version: 7,
+		Name:    "fine_due_dates_and_status",
+		// rokovi se racunaju od dana prekrsaja: 8 dana za placanje pola
+		// iznosa, 30 dana do dospeca (vidi service.DiscountDays/DueDays)
+		Up: `
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS status         text NOT NULL DEFAULT 'ISSUED';
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS discount_until timestamptz;
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS due_date       timestamptz;
+ALTER TABLE fines ADD COLUMN IF NOT EXISTS paid_amount    decimal;
+UPDATE fines SET
+	status         = CASE WHEN is_paid THEN 'PAID' ELSE 'ISSUED' END,
+	discount_until = date + interval '8 days',
+	due_date       = date + interval '30 days',
+	paid_amount    = CASE WHEN is_paid THEN amount END
+WHERE due_date IS NULL;
+CREATE INDEX IF NOT EXISTS idx_fines_status_due_date ON fines (status, due_date);
+
+CREATE TABLE IF NOT EXISTS fine_status_changes (
+	id          text PRIMARY KEY,
+	created_at  timestamptz,
+	updated_at  timestamptz,
+	fine_id     text NOT NULL,
+	from_status text NOT NULL,
+	to_status   text NOT NULL,
+	changed_at  timestamptz NOT NULL,
+	reason      text NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS idx_fine_status_changes_fine_id ON fine_status_changes (fine_id);
+`,
+		Down: `
+DROP TABLE IF EXISTS fine_status_changes;
+DROP INDEX IF EXISTS idx_fines_status_due_date;
+ALTER TABLE fines DROP COLUMN IF EXISTS paid_amount;
+ALTER TABLE fines DROP COLUMN IF EXISTS due_date;
+ALTER TABLE fines DROP COLUMN IF EXISTS discount_until;
+ALTER TABLE fines DROP COLUMN IF EXISTS status;
+`,
+	},
+	{
+		Version: 8,
+		Name:    "violation_status_and_appeals",
+		Up: `
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS status          text NOT NULL DEFAULT 'ACTIVE';
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS voided_at       timestamptz;
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS voided_by       text;
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS void_reason     text;
+ALTER TABLE violations ADD COLUMN IF NOT EXISTS points_reversed boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS appeals (
+	id           text PRIMARY KEY,
+	created_at   timestamptz,
+	updated_at   timestamptz,
+	violation_id text NOT NULL,
+	driver_id    text NOT NULL,
+	submitted_by text NOT NULL,
+	reason       text NOT NULL,
+	attachments  jsonb,
+	status       text NOT NULL,
+	reviewer_id  text,
+	decision     text,
+	reviewed_at  timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_appeals_violation_id ON appeals (violation_id);
+CREATE INDEX IF NOT EXISTS idx_appeals_driver_id ON appeals (driver_id);
+-- najvise jedna otvorena zalba po prekrsaju
+CREATE UNIQUE INDEX IF NOT EXISTS idx_appeals_open_violation ON appeals (violation_id)
+	WHERE status IN ('SUBMITTED', 'UNDER_REVIEW');
+`,
+		Down: `
+DROP TABLE IF EXISTS appeals;
+ALTER TABLE violations DROP COLUMN IF EXISTS points_reversed;
+ALTER TABLE violations DROP COLUMN IF EXISTS void_reason;
+ALTER TABLE violations DROP COLUMN IF EXISTS voided_by;
+ALTER TABLE violations DROP COLUMN IF EXISTS voided_at;
+ALTER TABLE violations DROP COLUMN IF EXISTS status;
+`,
+	},
+	{
+		Version: 9,
+		Name:    "create_outbox_messages",
+		Up: `
+CREATE TABLE IF NOT EXISTS outbox_messages (
+	id              text PRIMARY KEY,
+	created_at      timestamptz,
+	updated_at      timestamptz,
+	topic           text NOT NULL,
+	aggregate_id    text NOT NULL,
+	payload         jsonb NOT NULL,
+	status          text NOT NULL DEFAULT 'PENDING',
+	attempts        integer NOT NULL DEFAULT 0,
+	next_attempt_at timestamptz NOT NULL,
+	last_error      text,
+	delivered_at    timestamptz
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_aggregate_id ON outbox_messages (aggregate_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_messages_pending ON outbox_messages (next_attempt_at)
+	WHERE status = 'PENDING';
+`,
+		Down: `
+DROP TABLE IF EXISTS outbox_messages;
+`,
+	},
+	{
+		Version: 10,
+		Name:    "registry_record_source",
+		// user_id je prazan za vlasnike bez naloga, pa je jedinstven samo kad postoji
+		Up: `
+ALTER TABLE owners ADD COLUMN IF NOT EXISTS source text NOT NULL DEFAULT 'LOCAL';
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS source text NOT NULL DEFAULT 'LOCAL';
+DROP INDEX IF EXISTS idx_owners_user_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_owners_user_id ON owners (user_id)
+	WHERE user_id IS NOT NULL AND user_id <> '';
+`,
+		Down: `
+DROP INDEX IF EXISTS idx_owners_user_id;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_owners_user_id ON owners (user_id);
+ALTER TABLE vehicles DROP COLUMN IF EXISTS source;
+ALTER TABLE owners DROP COLUMN IF EXISTS source;
+`,
+	},
+	{
+		Version: 11,
+		Name:    "registry_sync",
+		Up: `
+ALTER TABLE drivers ADD COLUMN IF NOT EXISTS source text NOT NULL DEFAULT 'LOCAL';
+ALTER TABLE owners ADD COLUMN IF NOT EXISTS synced_at timestamptz;
+ALTER TABLE vehicles ADD COLUMN IF NOT EXISTS synced_at timestamptz;
+ALTER TABLE drivers ADD COLUMN IF NOT EXISTS synced_at timestamptz;
+
+CREATE TABLE IF NOT EXISTS registry_syncs (
+	id                text PRIMARY KEY,
+	cursor            timestamptz,
+	last_sync_at      timestamptz,
+	last_full_sync_at timestamptz,
+	last_attempt_at   timestamptz,
+	last_error        text NOT NULL DEFAULT '',
+	owners            bigint NOT NULL DEFAULT 0,
+	vehicles          bigint NOT NULL DEFAULT 0,
+	drivers           bigint NOT NULL DEFAULT 0
+);
+`,
+		Down: `
+DROP TABLE IF EXISTS registry_syncs;
+ALTER TABLE drivers DROP COLUMN IF EXISTS synced_at;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS synced_at;
+ALTER TABLE owners DROP COLUMN IF EXISTS synced_at;
+ALTER TABLE drivers DROP COLUMN IF EXISTS source;
+`,
+	},
+	{
+		Version: 12,
+		Name:    "drop_ownership_transfers",
+		// prenose vlasnistva vodi MUP; lokalna tabela se vise ne koristi
+		Up: `
+DROP TABLE IF EXISTS ownership_transfers;
+`,
+		Down: `
+CREATE TABLE IF NOT EXISTS ownership_transfers (
+	id               text PRIMARY KEY,
+	created_at       timestamptz,
+	updated_at       timestamptz,
+	vehicle_id       text,
+	owner_old_id     text,
+	owner_new_id     text,
+	date_of_transfer timestamptz,
+	CONSTRAINT fk_ownership_transfers_vehicle FOREIGN KEY (vehicle_id) REFERENCES vehicles (id),
+	CONSTRAINT fk_ownership_transfers_owner_old FOREIGN KEY (owner_old_id) REFERENCES owners (id),
+	CONSTRAINT fk_ownership_transfers_owner_new FOREIGN KEY (owner_new_id) REFERENCES owners (id)
+);
+CREATE INDEX IF NOT EXISTS idx_ownership_transfers_vehicle_id ON ownership_transfers (vehicle_id);
+CREATE INDEX IF NOT EXISTS idx_ownership_transfers_owner_old_id ON ownership_transfers (owner_old_id);
+CREATE INDEX IF NOT EXISTS idx_ownership_transfers_owner_new_id ON ownership_transfers (owner_new_id);
+`,
+	},
+	{
+		Version: 13,
+		Name:    "backfill_violation_tariffs",
+		// prekrsaji izdati pre tarifa dobijaju osnovnu tarifu svog tipa, da bi
+		// ponistavanje vratilo poene koji su tada upisani u MUP
+		Up: `
+UPDATE violations v SET tariff_id = t.id, points = t.points
+FROM tariffs t
+WHERE t.id IN ('TAR-MINOR-1', 'TAR-MAJOR-1', 'TAR-CRITICAL-1')
+	AND t.type_of_violation = v.type_of_violation
+	AND v.tariff_id IS NULL;
+`,
+		// popunjene vrednosti su tacne i pod verzijom 12; nema sta da se vrati
+		Down: `SELECT 1;`,
+	},
+}
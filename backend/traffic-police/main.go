@@ -1,53 +1,28 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
+	"common/jwtauth"
+	"common/mupclient"
+	"common/policy"
 	"traffic-police/config"
 	"traffic-police/data"
 	"traffic-police/models"
 	"traffic-police/service"
 )
 
-//
-// ===== MUP DTOs =====
-//
-
-type MupOwner struct {
-	ID        string `json:"id"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Address   string `json:"address"`
-	JMBG      string `json:"jmbg"`
-	Email     string `json:"email"`
-}
-
-type MupVehicle struct {
-	ID           string   `json:"id"`
-	Mark         string   `json:"mark"`
-	Model        string   `json:"model"`
-	Registration string   `json:"registration"`
-	Year         int      `json:"year"`
-	Color        string   `json:"color"`
-	IsStolen     bool     `json:"isStolen"`
-	Owner        MupOwner `json:"owner"`
-}
-
-type MupDriver struct {
-	ID                      string   `json:"id"`
-	IsSuspended             bool     `json:"isSuspended"`
-	NumberOfViolationPoints int      `json:"numberOfViolationPoints"`
-	Picture                 string   `json:"picture"`
-	Owner                   MupOwner `json:"owner"`
-}
-
 type CreatePoliceRequest struct {
 	FirstName   string      `json:"firstName"`
 	LastName    string      `json:"lastName"`
@@ -57,66 +32,172 @@ type CreatePoliceRequest struct {
 	IsSuspended bool        `json:"isSuspended"`
 }
 
-type pointsReq struct {
-	Delta int `json:"delta"`
+// deliverOutbox salje outbox poruku MUP-u. violationId je MUP-u kljuc
+// idempotentnosti, pa ponovljena isporuka ne racuna poene dvaput.
+func deliverOutbox(mup *mupclient.Client) service.OutboxDeliverer {
+	return func(ctx context.Context, m *models.OutboxMessage) error {
+		if m.Topic != models.OutboxDriverPoints {
+			return fmt.Errorf("unknown outbox topic %q", m.Topic)
+		}
+		req := mupclient.PointsRequest{Delta: m.Payload.Delta, ViolationID: m.Payload.ViolationID}
+		if !m.Payload.Date.IsZero() {
+			req.Date = &m.Payload.Date
+		}
+		_, err := mup.AddPoints(ctx, m.Payload.DriverID, req)
+		return err
+	}
 }
 
-//
-// ===== HTTP helpers =====
-//
-
-func mupGet[T any](client *http.Client, baseURL, path string) (*T, int, error) {
-	req, err := http.NewRequest("GET", baseURL+path, nil)
-	if err != nil {
-		return nil, 0, err
+// fetchRegistry preuzima izmene MUP registra i prevodi ih u lokalne modele.
+func fetchRegistry(mup *mupclient.Client) service.RegistryFetcher {
+	return func(ctx context.Context, since time.Time, page string) (*service.RegistryBatch, error) {
+		ch, err := mup.RegistryChanges(ctx, since, page)
+		if err != nil {
+			return nil, err
+		}
+		b := &service.RegistryBatch{Full: ch.Full, Cursor: ch.Cursor, Next: ch.Next}
+		for _, o := range ch.Owners {
+			b.Owners = append(b.Owners, models.Owner{
+				BaseModel: models.BaseModel{ID: o.ID},
+				FirstName: o.FirstName,
+				LastName:  o.LastName,
+				Address:   o.Address,
+				JMBG:      o.JMBG,
+				Email:     o.Email,
+			})
+		}
+		for _, v := range ch.Vehicles {
+			b.Vehicles = append(b.Vehicles, models.Vehicle{
+				BaseModel:    models.BaseModel{ID: v.ID},
+				Mark:         v.Mark,
+				Model:        v.Model,
+				Registration: v.Registration,
+				Year:         v.Year,
+				Color:        v.Color,
+				IsStolen:     v.IsStolen,
+				OwnerID:      v.OwnerID,
+			})
+		}
+		for _, d := range ch.Drivers {
+			b.Drivers = append(b.Drivers, models.Driver{
+				BaseModel:               models.BaseModel{ID: d.ID},
+				IsSuspended:             d.IsSuspended,
+				NumberOfViolationPoints: d.NumberOfViolationPoints,
+				Picture:                 d.Picture,
+				OwnerID:                 d.OwnerID,
+			})
+		}
+		return b, nil
 	}
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, 0, err
+}
+
+// verifyOffline odgovara na proveru vozila iz sinhronizovane kopije registra
+// kad MUP nije dostupan; syncedAt kaze koliko je kopija sveza.
+func verifyOffline(c *gin.Context, store *service.Store, req models.VehicleVerificationRequest) {
+	var st models.RegistrySync
+	if err := store.GetRegistrySync(&st); err != nil || st.LastSyncAt == nil {
+		c.JSON(503, gin.H{"error": "mup registry unavailable and no synced copy"})
+		return
 	}
-	defer res.Body.Close()
 
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		return nil, res.StatusCode, nil
+	valid, veh, err := store.VerifyVehicle(req)
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(200, gin.H{"valid": false, "reason": "vehicle not found", "offline": true, "syncedAt": st.LastSyncAt})
+	case errors.Is(err, service.ErrInvalid):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case err != nil:
+		c.JSON(500, gin.H{"error": err.Error()})
+	default:
+		c.JSON(200, gin.H{"valid": valid, "vehicle": veh, "offline": true, "syncedAt": st.LastSyncAt})
 	}
+}
 
-	var out T
-	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
-		return nil, res.StatusCode, err
+// pointsState je stanje isporuke poena za odgovor klijentu.
+func pointsState(delivered bool) models.OutboxStatus {
+	if delivered {
+		return models.OutboxDelivered
 	}
-	return &out, res.StatusCode, nil
+	return models.OutboxPending
 }
 
-func mupPatchJSON[T any](client *http.Client, baseURL, path string, body any) (*T, int, error) {
-	b, _ := json.Marshal(body)
-	req, err := http.NewRequest("PATCH", baseURL+path, bytes.NewReader(b))
-	if err != nil {
-		return nil, 0, err
+// notFoundOr500 vraca 404 ako zapis ne postoji, a 500 za ostale greske baze.
+func notFoundOr500(c *gin.Context, err error, what string) {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(404, gin.H{"error": what + " not found"})
+		return
 	}
-	req.Header.Set("Content-Type", "application/json")
+	c.JSON(500, gin.H{"error": err.Error()})
+}
 
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, 0, err
+// violationStatusQuery cita filter ?status= (prazan znaci svi prekrsaji) i
+// vraca 400 za nepoznat status umesto prazne liste.
+func violationStatusQuery(c *gin.Context) (models.ViolationStatus, bool) {
+	status := models.ViolationStatus(c.Query("status"))
+	if status != "" && !status.Valid() {
+		c.JSON(400, gin.H{"error": "status must be ACTIVE or VOIDED"})
+		return "", false
 	}
-	defer res.Body.Close()
+	return status, true
+}
 
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		return nil, res.StatusCode, nil
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// pageParams cita ?page= i ?pageSize= (podrazumevano 1 i 20, najvise 100).
+func pageParams(c *gin.Context) service.Page {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	size, _ := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(defaultPageSize)))
+	if page < 1 {
+		page = 1
+	}
+	if size < 1 || size > maxPageSize {
+		size = defaultPageSize
 	}
+	return service.Page{Number: page, Size: size}
+}
+
+// writePage salje stranicu kao obican niz, a ukupan broj u X-Total-Count.
+func writePage(c *gin.Context, p service.Page, total int64, list any) {
+	c.Header("X-Total-Count", strconv.FormatInt(total, 10))
+	c.Header("X-Page", strconv.Itoa(p.Number))
+	c.Header("X-Page-Size", strconv.Itoa(p.Size))
+	c.JSON(200, list)
+}
 
-	var out T
-	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
-		return nil, res.StatusCode, err
+// registryError mapira greske upisa vlasnika/vozila: 400 za neispravan unos,
+// 409 za duplikat, inace kao notFoundOr500.
+func registryError(c *gin.Context, err error, what string) {
+	switch {
+	case errors.Is(err, service.ErrInvalid):
+		c.JSON(400, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrDuplicate):
+		c.JSON(409, gin.H{"error": err.Error()})
+	default:
+		notFoundOr500(c, err, what)
 	}
-	return &out, res.StatusCode, nil
 }
 
 func main() {
 	cfg := config.GetConfig()
 
-	httpClient := &http.Client{
+	jwtCfg := jwtauth.Config{Issuer: cfg.Issuer, Keys: jwtauth.NewRemoteKeySet(cfg.JWKSURL)}
+
+	mup := mupclient.New(cfg.MupBaseURL, &http.Client{
 		Timeout: time.Duration(cfg.MupTimeoutMs) * time.Millisecond,
+		Transport: jwtauth.NewServiceTransport(jwtauth.ClientCredentials{
+			URL:          cfg.AuthURL + "/token",
+			ClientID:     "traffic-police",
+			ClientSecret: cfg.ServiceSecret,
+		}),
+	})
+	mup.Retry.Attempts = cfg.MupRetryAttempts
+	if cfg.MupCacheTTLMs > 0 {
+		mup.Cache.TTL = time.Duration(cfg.MupCacheTTLMs) * time.Millisecond
+	} else {
+		mup.Cache = nil
 	}
 
 	// DB
@@ -124,13 +205,40 @@ func main() {
 	if err != nil {
 		panic(fmt.Sprintf("Failed to connect to database: %v", err))
 	}
-	if err = data.AutoMigrate(db); err != nil {
-		panic(err)
+	migrator := data.Migrator(db)
+
+	// "server migrate up|down [n]|status" samo izvrsava migracije i izlazi
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrator.Command(context.Background(), os.Args[2:], os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if _, err = migrator.Up(context.Background()); err != nil {
+		panic(fmt.Sprintf("Failed to migrate database: %v", err))
 	}
 
 	store := service.NewStore(db)
 
+	// kazne kojima je prosao rok prelaze u OVERDUE
+	go store.RunOverdueJob(context.Background(), time.Hour)
+
+	deliver := deliverOutbox(mup)
+	go store.RunOutboxDispatcher(context.Background(), 5*time.Second, deliver)
+
+	// kopija MUP registra (vlasnici, vozila, vozaci)
+	syncRegistry := fetchRegistry(mup)
+	if cfg.RegistrySyncMinutes > 0 {
+		go store.RunRegistrySync(context.Background(), time.Duration(cfg.RegistrySyncMinutes)*time.Minute, syncRegistry)
+	}
+
+	routes := routePolicy(store)
+
 	r := gin.Default()
+	r.Use(
+		jwtauth.Middleware(jwtCfg, routes.PublicPaths()...),
+		policy.Enforce(routes, policeRank(store)),
+	)
 
 	r.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
@@ -202,6 +310,106 @@ func main() {
 		c.JSON(200, u)
 	})
 
+	// ===== Owners / Vehicles =====
+	// Lokalna evidencija; rucno se unose samo zapisi kojih nema u MUP registru.
+	r.GET("/owners", func(c *gin.Context) {
+		p := pageParams(c)
+		list := []models.Owner{}
+		total, err := store.ListOwners(p, &list)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		writePage(c, p, total, list)
+	})
+
+	r.GET("/owners/:id", func(c *gin.Context) {
+		var o models.Owner
+		if err := store.GetOwner(c.Param("id"), &o); err != nil {
+			notFoundOr500(c, err, "owner")
+			return
+		}
+		c.JSON(200, o)
+	})
+
+	r.POST("/owners", func(c *gin.Context) {
+		var o models.Owner
+		if err := c.ShouldBindJSON(&o); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if err := store.CreateOwner(&o); err != nil {
+			registryError(c, err, "owner")
+			return
+		}
+		c.JSON(201, o)
+	})
+
+	r.GET("/vehicles", func(c *gin.Context) {
+		p := pageParams(c)
+		list := []models.Vehicle{}
+		total, err := store.ListVehicles(p, &list)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		writePage(c, p, total, list)
+	})
+
+	r.GET("/vehicles/:id", func(c *gin.Context) {
+		var v models.Vehicle
+		if err := store.GetVehicle(c.Param("id"), &v); err != nil {
+			notFoundOr500(c, err, "vehicle")
+			return
+		}
+		c.JSON(200, v)
+	})
+
+	// POST /vehicles  vozilo koje nije u MUP registru (npr. strane tablice)
+	r.POST("/vehicles", func(c *gin.Context) {
+		var v models.Vehicle
+		if err := c.ShouldBindJSON(&v); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		reg := service.NormalizeRegistration(v.Registration)
+		if reg != "" {
+			_, err := mup.GetVehicle(c.Request.Context(), reg)
+			if err == nil {
+				c.JSON(409, gin.H{"error": "vehicle is registered in MUP registry"})
+				return
+			}
+			if !errors.Is(err, mupclient.ErrNotFound) {
+				c.JSON(503, gin.H{"error": "mup registry unavailable"})
+				return
+			}
+		}
+
+		if err := store.CreateVehicle(&v); err != nil {
+			registryError(c, err, "vehicle")
+			return
+		}
+		c.JSON(201, v)
+	})
+
+	// POST /vehicles/search  body: { "mark", "model", "color", "registration" }
+	r.POST("/vehicles/search", func(c *gin.Context) {
+		var req models.SearchVehicleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		p := pageParams(c)
+		list := []models.Vehicle{}
+		total, err := store.SearchVehicles(req, p, &list)
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		writePage(c, p, total, list)
+	})
+
 	// ===== VEHICLE VERIFY (inter-service) =====
 	r.POST("/vehicles/verify", func(c *gin.Context) {
 		var req models.VehicleVerificationRequest
@@ -210,25 +418,34 @@ func main() {
 			return
 		}
 
-		veh, st, err := mupGet[MupVehicle](httpClient, cfg.MupBaseURL, "/vehicles/"+req.Registration)
-		if err != nil {
-			c.JSON(500, gin.H{"error": "mup request failed"})
+		ctx := c.Request.Context()
+		veh, err := mup.GetVehicle(ctx, req.Registration)
+		if errors.Is(err, mupclient.ErrNotFound) {
+			c.JSON(200, gin.H{"valid": false, "reason": "vehicle not found"})
 			return
 		}
-		if st == 404 || veh == nil {
-			c.JSON(200, gin.H{"valid": false, "reason": "vehicle not found"})
+		if mupclient.Unavailable(err) {
+			verifyOffline(c, store, req)
 			return
 		}
-
-		vehByJmbg, st2, err := mupGet[MupVehicle](httpClient, cfg.MupBaseURL, "/vehicles/owner/"+req.JMBG)
 		if err != nil {
 			c.JSON(500, gin.H{"error": "mup request failed"})
 			return
 		}
-		if st2 == 404 || vehByJmbg == nil {
+
+		vehByJmbg, err := mup.GetVehicleByOwnerJMBG(ctx, req.JMBG)
+		if errors.Is(err, mupclient.ErrNotFound) {
 			c.JSON(200, gin.H{"valid": false, "reason": "no vehicle for owner jmbg"})
 			return
 		}
+		if mupclient.Unavailable(err) {
+			verifyOffline(c, store, req)
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": "mup request failed"})
+			return
+		}
 
 		valid := (veh.Owner.JMBG == req.JMBG) && (veh.Registration == vehByJmbg.Registration)
 		c.JSON(200, gin.H{"valid": valid, "vehicle": veh})
@@ -236,21 +453,26 @@ func main() {
 
 	// ===== Violations (inter-service business rules) =====
 	r.POST("/violations", func(c *gin.Context) {
-		var v models.Violation
-		if err := c.ShouldBindJSON(&v); err != nil {
+		var req models.CreateViolationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
+		v := req.Violation
+		// ID dodeljuje servis, a prekrsaj belezi policajac iz tokena
+		claims, _ := jwtauth.FromContext(c)
+		v.ID, v.PoliceID = "", claims.ID
 
 		// vehicleId comes as registration string
 		registration := fmt.Sprintf("%v", v.VehicleID)
-		veh, vSt, err := mupGet[MupVehicle](httpClient, cfg.MupBaseURL, "/vehicles/"+registration)
-		if err != nil {
-			c.JSON(500, gin.H{"error": "mup vehicles request failed"})
+		ctx := c.Request.Context()
+		veh, err := mup.GetVehicle(ctx, registration)
+		if errors.Is(err, mupclient.ErrNotFound) {
+			c.JSON(400, gin.H{"error": "invalid vehicle registration"})
 			return
 		}
-		if vSt == 404 || veh == nil {
-			c.JSON(400, gin.H{"error": "invalid vehicle registration"})
+		if err != nil {
+			c.JSON(500, gin.H{"error": "mup vehicles request failed"})
 			return
 		}
 		if veh.IsStolen {
@@ -258,75 +480,66 @@ func main() {
 		}
 
 		driverId := fmt.Sprintf("%v", v.DriverID)
-		driver, dSt, err := mupGet[MupDriver](httpClient, cfg.MupBaseURL, "/drivers/"+driverId)
-		if err != nil {
-			c.JSON(500, gin.H{"error": "mup drivers request failed"})
+		driver, err := mup.GetDriver(ctx, driverId)
+		if errors.Is(err, mupclient.ErrNotFound) {
+			c.JSON(400, gin.H{"error": "invalid driver id"})
 			return
 		}
-		if dSt == 404 || driver == nil {
-			c.JSON(400, gin.H{"error": "invalid driver id"})
+		if err != nil {
+			c.JSON(500, gin.H{"error": "mup drivers request failed"})
 			return
 		}
 		if driver.IsSuspended {
-			c.JSON(409, gin.H{"error": "driver is suspended - cannot create violation"})
+			resp := gin.H{"error": "driver is suspended - cannot create violation"}
+			if list, err := mup.ListSuspensions(ctx, driverId); err == nil {
+				active := []mupclient.Suspension{}
+				for _, s := range list {
+					if s.Active {
+						active = append(active, s)
+					}
+				}
+				resp["suspensions"] = active
+			}
+			c.JSON(409, resp)
 			return
 		}
 
-		if err := store.CreateViolation(&v); err != nil {
+		var fine models.Fine
+		if err := store.CreateViolation(&v, req.FineAmount, &fine); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
 
-		// points
-		delta := 1
-		switch v.TypeOfViolation {
-		case "MAJOR":
-			delta = 3
-		case "CRITICAL":
-			delta = 5
-		default:
-			delta = 1
-		}
-
-		updatedDriver, pSt, err := mupPatchJSON[MupDriver](
-			httpClient,
-			cfg.MupBaseURL,
-			"/drivers/"+driverId+"/points",
-			pointsReq{Delta: delta},
-		)
+		// poeni su vec u outbox-u; ako MUP sada ne odgovori, dispecer ponavlja
+		delivered, _ := store.DispatchFor(c.Request.Context(), v.ID, deliver)
+		c.JSON(201, gin.H{
+			"violation":    v,
+			"fine":         fine,
+			"vehicle":      veh,
+			"pointsUpdate": pointsState(delivered),
+		})
+	})
 
-		if err != nil || pSt >= 400 || updatedDriver == nil {
-			c.JSON(201, gin.H{
-				"violation": v,
-				"vehicle":   veh,
-				"warning":   "violation created but mup points update failed",
-			})
+	// GET /drivers/:id/suspensions  istorija suspenzija iz MUP-a (razlog, rok, izdavalac)
+	r.GET("/drivers/:id/suspensions", func(c *gin.Context) {
+		list, err := mup.ListSuspensions(c.Request.Context(), c.Param("id"))
+		if errors.Is(err, mupclient.ErrNotFound) {
+			c.JSON(404, gin.H{"error": "driver not found"})
 			return
 		}
-
-		c.JSON(201, gin.H{
-			"violation": v,
-			"vehicle":   veh,
-			"driver":    updatedDriver,
-		})
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
 	})
 
-	// Add this to your main.go
+	// GET /drivers/:id/report  tezine rizika dolaze iz tarife
 	r.GET("/drivers/:id/report", func(c *gin.Context) {
-		var violations []models.Violation
-		store.ListViolationsByDriver(c.Param("id"), &violations)
-
-		score := 0
-		for _, v := range violations {
-			if v.TypeOfViolation == "CRITICAL" {
-				score += 10
-			}
-			if v.TypeOfViolation == "MAJOR" {
-				score += 5
-			}
-			if v.TypeOfViolation == "MINOR" {
-				score += 2
-			}
+		score, total, err := store.DriverRiskScore(c.Param("id"))
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
 		}
 
 		riskLevel := "LOW"
@@ -338,15 +551,43 @@ func main() {
 
 		c.JSON(200, gin.H{
 			"driver_id":        c.Param("id"),
-			"total_violations": len(violations),
+			"total_violations": total,
 			"risk_score":       score,
 			"risk_level":       riskLevel,
 		})
 	})
 
+	// ===== Tariffs =====
+	r.GET("/tariffs", func(c *gin.Context) {
+		list := []models.Tariff{}
+		if err := store.ListTariffs(&list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
+	})
+
+	// POST /tariffs  nova verzija tarife, vazi od effectiveFrom
+	r.POST("/tariffs", func(c *gin.Context) {
+		var t models.Tariff
+		if err := c.ShouldBindJSON(&t); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if err := store.CreateTariff(&t); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(201, t)
+	})
+
 	r.GET("/violations", func(c *gin.Context) {
+		status, ok := violationStatusQuery(c)
+		if !ok {
+			return
+		}
 		var list []models.Violation
-		if err := store.ListViolations(&list); err != nil {
+		if err := store.ListViolations(status, &list); err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
@@ -363,37 +604,346 @@ func main() {
 	})
 
 	r.GET("/violations/driver/:driverId", func(c *gin.Context) {
+		status, ok := violationStatusQuery(c)
+		if !ok {
+			return
+		}
 		var list []models.Violation
-		if err := store.ListViolationsByDriver(c.Param("driverId"), &list); err != nil {
+		if err := store.ListViolationsByDriver(c.Param("driverId"), status, &list); err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(200, list)
 	})
 
-	// ===== Transfers (ostavljeno kao ranije) =====
-	r.POST("/transfers", func(c *gin.Context) {
-		var t models.OwnershipTransfer
-		if err := c.ShouldBindJSON(&t); err != nil {
+	// ===== Fines =====
+	r.GET("/fines/driver/:driverId", func(c *gin.Context) {
+		list := []models.Fine{}
+		if err := store.ListFinesByDriver(c.Param("driverId"), &list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
+	})
+
+	r.GET("/fines/driver/:driverId/balance", func(c *gin.Context) {
+		var b models.FineBalance
+		if err := store.FineBalance(c.Param("driverId"), &b); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, b)
+	})
+
+	r.GET("/fines/violation/:violationId", func(c *gin.Context) {
+		var f models.Fine
+		if err := store.GetFineByViolation(c.Param("violationId"), &f); err != nil {
+			notFoundOr500(c, err, "fine")
+			return
+		}
+		c.JSON(200, f)
+	})
+
+	// PATCH /fines/:id/pay
+	r.PATCH("/fines/:id/pay", func(c *gin.Context) {
+		var f models.Fine
+		if err := store.PayFine(c.Param("id"), &f); err != nil {
+			if errors.Is(err, service.ErrFineAlreadyPaid) || errors.Is(err, service.ErrFineCancelled) {
+				c.JSON(409, gin.H{"error": err.Error()})
+				return
+			}
+			notFoundOr500(c, err, "fine")
+			return
+		}
+		c.JSON(200, f)
+	})
+
+	r.GET("/fines/:id", func(c *gin.Context) {
+		var f models.Fine
+		if err := store.GetFine(c.Param("id"), &f); err != nil {
+			notFoundOr500(c, err, "fine")
+			return
+		}
+		c.JSON(200, f)
+	})
+
+	// GET /fines/:id/history  istorija statusa (ISSUED -> OVERDUE -> PAID...)
+	r.GET("/fines/:id/history", func(c *gin.Context) {
+		var f models.Fine
+		if err := store.GetFine(c.Param("id"), &f); err != nil {
+			notFoundOr500(c, err, "fine")
+			return
+		}
+		list := []models.FineStatusChange{}
+		if err := store.FineHistory(f.ID, &list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
+	})
+
+	// GET /fines/:id/payment-slip  podaci za nalog za uplatu
+	r.GET("/fines/:id/payment-slip", func(c *gin.Context) {
+		var slip models.PaymentSlip
+		if err := store.PaymentSlip(c.Param("id"), cfg.FineRecipient, cfg.FineAccount, &slip); err != nil {
+			notFoundOr500(c, err, "fine")
+			return
+		}
+		c.JSON(200, slip)
+	})
+
+	// POST /fines/payments/import  CSV izvod (reference,amount[,date]) kao
+	// multipart polje "file" ili kao telo zahteva
+	r.POST("/fines/payments/import", func(c *gin.Context) {
+		var body io.Reader = c.Request.Body
+		if fh, err := c.FormFile("file"); err == nil {
+			f, err := fh.Open()
+			if err != nil {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+			defer f.Close()
+			body = f
+		}
+
+		var report models.ReconciliationReport
+		if err := store.ReconcileStatement(body, &report); err != nil {
+			if errors.Is(err, service.ErrStatementFormat) {
+				c.JSON(400, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, report)
+	})
+
+	// PATCH /violations/:id/void  body: { "reason": "..." }
+	r.PATCH("/violations/:id/void", func(c *gin.Context) {
+		var req models.VoidViolationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
-		if err := store.CreateTransfer(&t); err != nil {
+
+		claims, _ := jwtauth.FromContext(c)
+		var v models.Violation
+		if err := store.VoidViolation(c.Param("id"), claims.ID, req.Reason, &v); err != nil {
+			switch {
+			case errors.Is(err, service.ErrVoidReason):
+				c.JSON(400, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrViolationVoided):
+				c.JSON(409, gin.H{"error": err.Error()})
+			default:
+				notFoundOr500(c, err, "violation")
+			}
+			return
+		}
+
+		// kompenzacija (oduzimanje poena) je upisana u outbox zajedno sa ponistavanjem
+		delivered, _ := store.DispatchFor(c.Request.Context(), v.ID, deliver)
+		c.JSON(200, gin.H{"violation": v, "pointsUpdate": pointsState(delivered)})
+	})
+
+	// ===== Appeals =====
+	// POST /violations/:id/appeals  gradjanin se zali na svoj prekrsaj
+	r.POST("/violations/:id/appeals", func(c *gin.Context) {
+		var req models.AppealRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
 			c.JSON(400, gin.H{"error": err.Error()})
 			return
 		}
-		c.JSON(201, t)
+
+		claims, _ := jwtauth.FromContext(c)
+		var a models.Appeal
+		if err := store.CreateAppeal(c.Param("id"), claims.ID, req, &a); err != nil {
+			switch {
+			case errors.Is(err, service.ErrAppealReason):
+				c.JSON(400, gin.H{"error": err.Error()})
+			case errors.Is(err, service.ErrAppealOpen), errors.Is(err, service.ErrViolationVoided):
+				c.JSON(409, gin.H{"error": err.Error()})
+			default:
+				notFoundOr500(c, err, "violation")
+			}
+			return
+		}
+		c.JSON(201, a)
 	})
 
-	r.GET("/transfers", func(c *gin.Context) {
-		var list []models.OwnershipTransfer
-		if err := store.ListTransfers(&list); err != nil {
+	// GET /appeals?status=SUBMITTED
+	r.GET("/appeals", func(c *gin.Context) {
+		list := []models.Appeal{}
+		if err := store.ListAppeals(models.AppealStatus(c.Query("status")), &list); err != nil {
 			c.JSON(500, gin.H{"error": err.Error()})
 			return
 		}
 		c.JSON(200, list)
 	})
 
+	r.GET("/appeals/:id", func(c *gin.Context) {
+		var a models.Appeal
+		if err := store.GetAppeal(c.Param("id"), &a); err != nil {
+			notFoundOr500(c, err, "appeal")
+			return
+		}
+		c.JSON(200, a)
+	})
+
+	// PATCH /appeals/:id/review  zalba prelazi u UNDER_REVIEW
+	r.PATCH("/appeals/:id/review", func(c *gin.Context) {
+		claims, _ := jwtauth.FromContext(c)
+		var a models.Appeal
+		if err := store.StartAppealReview(c.Param("id"), claims.ID, &a); err != nil {
+			if errors.Is(err, service.ErrAppealReviewer) {
+				c.JSON(403, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, service.ErrAppealState) {
+				c.JSON(409, gin.H{"error": err.Error()})
+				return
+			}
+			notFoundOr500(c, err, "appeal")
+			return
+		}
+		c.JSON(200, a)
+	})
+
+	// PATCH /appeals/:id/decision  body: { "accept": true, "note": "..." }
+	r.PATCH("/appeals/:id/decision", func(c *gin.Context) {
+		var req models.AppealDecisionRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+
+		claims, _ := jwtauth.FromContext(c)
+		var (
+			a models.Appeal
+			v models.Violation
+		)
+		if err := store.DecideAppeal(c.Param("id"), claims.ID, req, &a, &v); err != nil {
+			if errors.Is(err, service.ErrAppealReviewer) {
+				c.JSON(403, gin.H{"error": err.Error()})
+				return
+			}
+			if errors.Is(err, service.ErrAppealState) || errors.Is(err, service.ErrViolationVoided) {
+				c.JSON(409, gin.H{"error": err.Error()})
+				return
+			}
+			notFoundOr500(c, err, "appeal")
+			return
+		}
+		if !req.Accept {
+			c.JSON(200, gin.H{"appeal": a})
+			return
+		}
+
+		delivered, _ := store.DispatchFor(c.Request.Context(), v.ID, deliver)
+		c.JSON(200, gin.H{"appeal": a, "violation": v, "pointsUpdate": pointsState(delivered)})
+	})
+
+	// ===== Me (gradjanin, driver_id iz tokena) =====
+	r.GET("/me/violations", func(c *gin.Context) {
+		claims, _ := jwtauth.FromContext(c)
+		list := []models.Violation{}
+		if claims == nil || claims.DriverID == "" {
+			c.JSON(200, list)
+			return
+		}
+		if err := store.ListViolationsByDriver(claims.DriverID, "", &list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
+	})
+
+	r.GET("/me/fines", func(c *gin.Context) {
+		claims, _ := jwtauth.FromContext(c)
+		list := []models.Fine{}
+		if claims == nil || claims.DriverID == "" {
+			c.JSON(200, list)
+			return
+		}
+		if err := store.ListFinesByDriver(claims.DriverID, &list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
+	})
+
+	r.GET("/me/fines/balance", func(c *gin.Context) {
+		claims, _ := jwtauth.FromContext(c)
+		b := models.FineBalance{}
+		if claims == nil || claims.DriverID == "" {
+			c.JSON(200, b)
+			return
+		}
+		if err := store.FineBalance(claims.DriverID, &b); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, b)
+	})
+
+	r.GET("/me/appeals", func(c *gin.Context) {
+		claims, _ := jwtauth.FromContext(c)
+		list := []models.Appeal{}
+		if claims == nil || claims.DriverID == "" {
+			c.JSON(200, list)
+			return
+		}
+		if err := store.ListAppealsByDriver(claims.DriverID, &list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
+	})
+
+	// GET /mup/stats  stanje breakera, ponavljanja i pogoci kesa za pozive MUP-u
+	r.GET("/mup/stats", func(c *gin.Context) {
+		c.JSON(200, mup.Stats())
+	})
+
+	// ===== Registry sync =====
+	r.GET("/registry/sync", func(c *gin.Context) {
+		var st models.RegistrySync
+		if err := store.GetRegistrySync(&st); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, st)
+	})
+
+	// POST /registry/sync?full=true  sinhronizacija odmah (full: ceo registar)
+	r.POST("/registry/sync", func(c *gin.Context) {
+		var st models.RegistrySync
+		err := store.SyncRegistry(c.Request.Context(), c.Query("full") == "true", syncRegistry, &st)
+		if mupclient.Unavailable(err) {
+			c.JSON(503, gin.H{"error": err.Error()})
+			return
+		}
+		if err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, st)
+	})
+
+	// ===== Outbox =====
+	// GET /outbox/stuck  poruke za MUP koje vise puta nisu isporucene
+	r.GET("/outbox/stuck", func(c *gin.Context) {
+		list := []models.OutboxMessage{}
+		if err := store.ListStuckOutbox(&list); err != nil {
+			c.JSON(500, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(200, list)
+	})
+
+	if err := routes.Verify(r.Routes()); err != nil {
+		panic(err)
+	}
+
 	addr := fmt.Sprintf("%s:%d", cfg.ServiceHost, cfg.ServicePort)
 	if err := r.Run(addr); err != nil {
 		log.Fatal("greska prilikom pokretanja servera: ", err)
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"traffic-police/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestViolationStatusQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for query, want := range map[string]models.ViolationStatus{"": "", "?status=ACTIVE": models.ViolationActive, "?status=VOIDED": models.ViolationVoided} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/violations"+query, nil)
+		if got, ok := violationStatusQuery(c); !ok || got != want {
+			t.Errorf("%q: status = %q, %v; want %q", query, got, ok, want)
+		}
+	}
+
+	for _, query := range []string{"?status=garbage", "?status=active"} {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/violations"+query, nil)
+		if _, ok := violationStatusQuery(c); ok || w.Code != 400 {
+			t.Errorf("%q: ok = %v, status = %d; want 400", query, ok, w.Code)
+		}
+	}
+}
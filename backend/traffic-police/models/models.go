@@ -1,10 +1,7 @@
 package models
 
 import (
-	"bytes"
-	"encoding/json"
 	"math/rand"
-	"net/http"
 	"time"
 
 	"gorm.io/gorm"
@@ -69,14 +66,24 @@ const (
 // ===== DB Models =====
 //
 
+// RecordSource kaze odakle je zapis u lokalnoj evidenciji vlasnika i vozila.
+type RecordSource string
+
+const (
+	SourceMup   RecordSource = "MUP"   // kopija zapisa iz MUP registra
+	SourceLocal RecordSource = "LOCAL" // rucno unet, ne postoji u MUP-u
+)
+
 type Owner struct {
 	BaseModel
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	Address   string `json:"address"`
-	JMBG      string `json:"jmbg" gorm:"uniqueIndex"`
-	Email     string `json:"email"`
-	UserID    string `json:"userId" gorm:"uniqueIndex"`
+	FirstName string       `json:"firstName"`
+	LastName  string       `json:"lastName"`
+	Address   string       `json:"address"`
+	JMBG      string       `json:"jmbg" gorm:"uniqueIndex"`
+	Email     string       `json:"email"`
+	UserID    string       `json:"userId"`
+	Source    RecordSource `json:"source" gorm:"type:text;default:LOCAL"`
+	SyncedAt  *time.Time   `json:"syncedAt,omitempty"`
 }
 
 type Driver struct {
@@ -86,6 +93,9 @@ type Driver struct {
 	Picture                 string `json:"picture"`
 	OwnerID                 string `json:"ownerId" gorm:"index"`
 	Owner                   Owner  `json:"owner" gorm:"foreignKey:OwnerID;references:ID"`
+
+	Source   RecordSource `json:"source" gorm:"type:text;default:LOCAL"`
+	SyncedAt *time.Time   `json:"syncedAt,omitempty"`
 }
 
 type PoliceProfile struct {
@@ -105,6 +115,35 @@ type Vehicle struct {
 	IsStolen     bool   `json:"isStolen"`
 	OwnerID      string `json:"ownerId" gorm:"index"`
 	Owner        Owner  `json:"owner" gorm:"foreignKey:OwnerID;references:ID"`
+
+	Source   RecordSource `json:"source" gorm:"type:text;default:LOCAL"`
+	SyncedAt *time.Time   `json:"syncedAt,omitempty"`
+}
+
+// RegistrySync je stanje sinhronizacije kopije MUP registra (jedan red).
+// Cursor je MUP-ov kursor za sledece izmene; Owners/Vehicles/Drivers su
+// brojevi zapisa primljenih u poslednjoj uspesnoj sinhronizaciji.
+type RegistrySync struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:text"`
+	Cursor         *time.Time `json:"cursor"`
+	LastSyncAt     *time.Time `json:"lastSyncAt"`
+	LastFullSyncAt *time.Time `json:"lastFullSyncAt"`
+	LastAttemptAt  *time.Time `json:"lastAttemptAt"`
+	LastError      string     `json:"lastError,omitempty"`
+	Owners         int        `json:"owners"`
+	Vehicles       int        `json:"vehicles"`
+	Drivers        int        `json:"drivers"`
+}
+
+type ViolationStatus string
+
+const (
+	ViolationActive ViolationStatus = "ACTIVE"
+	ViolationVoided ViolationStatus = "VOIDED"
+)
+
+func (s ViolationStatus) Valid() bool {
+	return s == ViolationActive || s == ViolationVoided
 }
 
 type Violation struct {
@@ -115,25 +154,128 @@ type Violation struct {
 	DriverID        string          `json:"driverId" gorm:"index"`
 	VehicleID       string          `json:"vehicleId" gorm:"index"`
 	PoliceID        string          `json:"policeId" gorm:"index"`
+	// TariffID i Points belezi tarifu koja je vazila na dan prekrsaja.
+	TariffID string `json:"tariffId"`
+	Points   int    `json:"points"`
+	// Ponisten prekrsaj ostaje u bazi; PointsReversed znaci da su poeni
+	// vraceni u MUP-u.
+	Status         ViolationStatus `json:"status" gorm:"type:text;default:ACTIVE"`
+	VoidedAt       *time.Time      `json:"voidedAt,omitempty"`
+	VoidedBy       string          `json:"voidedBy,omitempty"`
+	VoidReason     string          `json:"voidReason,omitempty"`
+	PointsReversed bool            `json:"pointsReversed"`
+}
+
+// Tariff je jedna verzija tarife za vrstu prekrsaja. Vazi od EffectiveFrom
+// do sledece verzije za isti tip.
+type Tariff struct {
+	BaseModel
+	TypeOfViolation TypeOfViolation `json:"typeOfViolation" gorm:"type:text"`
+	EffectiveFrom   time.Time       `json:"effectiveFrom"`
+	Points          int             `json:"points"`
+	FineMin         float64         `json:"fineMin"`
+	FineMax         float64         `json:"fineMax"`
+	RiskWeight      int             `json:"riskWeight"`
 }
 
+type FineStatus string
+
+const (
+	FineIssued    FineStatus = "ISSUED"
+	FinePaid      FineStatus = "PAID"
+	FineOverdue   FineStatus = "OVERDUE"
+	FineCancelled FineStatus = "CANCELLED"
+)
+
 type Fine struct {
 	BaseModel
-	Amount      float64   `json:"amount"`
-	IsPaid      bool      `json:"isPaid"`
+	Amount      float64    `json:"amount"`
+	IsPaid      bool       `json:"isPaid"`
+	PaidAt      *time.Time `json:"paidAt,omitempty"`
+	PaidAmount  *float64   `json:"paidAmount,omitempty"`
+	Date        time.Time  `json:"date"`
+	ViolationID string     `json:"violationId" gorm:"uniqueIndex"`
+	// PaymentReference je poziv na broj (model 97) bez oznake modela.
+	PaymentReference string     `json:"paymentReference" gorm:"uniqueIndex"`
+	Status           FineStatus `json:"status" gorm:"type:text"`
+	// Do DiscountUntil se placa pola iznosa, posle DueDate raste uvecanje.
+	DiscountUntil time.Time `json:"discountUntil"`
+	DueDate       time.Time `json:"dueDate"`
+	// PayableAmount se racuna pri citanju (service.PayableAmount).
+	PayableAmount float64 `json:"payableAmount" gorm:"-"`
+}
+
+type AppealStatus string
+
+const (
+	AppealSubmitted   AppealStatus = "SUBMITTED"
+	AppealUnderReview AppealStatus = "UNDER_REVIEW"
+	AppealAccepted    AppealStatus = "ACCEPTED"
+	AppealRejected    AppealStatus = "REJECTED"
+	// AppealClosed: prekrsaj je ponisten pre odluke, pa o zalbi niko nije odlucivao
+	AppealClosed AppealStatus = "CLOSED"
+)
+
+type AppealAttachment struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// Appeal je zalba gradjanina na prekrsaj. O njoj odlucuje policajac
+// najviseg cina; prihvatanje ponistava prekrsaj.
+type Appeal struct {
+	BaseModel
+	ViolationID string             `json:"violationId" gorm:"index"`
+	DriverID    string             `json:"driverId" gorm:"index"`
+	SubmittedBy string             `json:"submittedBy"`
+	Reason      string             `json:"reason"`
+	Attachments []AppealAttachment `json:"attachments" gorm:"serializer:json;type:jsonb"`
+	Status      AppealStatus       `json:"status" gorm:"type:text"`
+	ReviewerID  string             `json:"reviewerId,omitempty"`
+	Decision    string             `json:"decision,omitempty"`
+	ReviewedAt  *time.Time         `json:"reviewedAt,omitempty"`
+}
+
+// FineStatusChange je jedan prelaz u istoriji statusa kazne.
+type FineStatusChange struct {
+	BaseModel
+	FineID     string     `json:"fineId" gorm:"index"`
+	FromStatus FineStatus `json:"fromStatus" gorm:"type:text"`
+	ToStatus   FineStatus `json:"toStatus" gorm:"type:text"`
+	ChangedAt  time.Time  `json:"changedAt"`
+	Reason     string     `json:"reason"`
+}
+
+type OutboxStatus string
+
+const (
+	OutboxPending   OutboxStatus = "PENDING"
+	OutboxDelivered OutboxStatus = "DELIVERED"
+)
+
+// OutboxDriverPoints je poruka za PATCH /drivers/:id/points u MUP-u.
+const OutboxDriverPoints = "mup.driver.points"
+
+// PointsPayload je sadrzaj OutboxDriverPoints poruke.
+type PointsPayload struct {
+	DriverID    string    `json:"driverId"`
+	ViolationID string    `json:"violationId"`
+	Delta       int       `json:"delta"`
 	Date        time.Time `json:"date"`
-	ViolationID string    `json:"violationId" gorm:"index"`
 }
 
-type OwnershipTransfer struct {
+// OutboxMessage je promena koju treba isporuciti drugom servisu. Upisuje se
+// u istoj transakciji kao i promena koja je izaziva.
+type OutboxMessage struct {
 	BaseModel
-	VehicleID      string    `json:"vehicleId" gorm:"index"`
-	Vehicle        Vehicle   `json:"vehicle" gorm:"foreignKey:VehicleID;references:ID"`
-	OwnerOldID     string    `json:"ownerOldId" gorm:"index"`
-	OwnerOld       Owner     `json:"ownerOld" gorm:"foreignKey:OwnerOldID;references:ID"`
-	OwnerNewID     string    `json:"ownerNewId" gorm:"index"`
-	OwnerNew       Owner     `json:"ownerNew" gorm:"foreignKey:OwnerNewID;references:ID"`
-	DateOfTransfer time.Time `json:"dateOfTransfer"`
+	Topic         string        `json:"topic"`
+	AggregateID   string        `json:"aggregateId" gorm:"index"`
+	Payload       PointsPayload `json:"payload" gorm:"serializer:json;type:jsonb"`
+	Status        OutboxStatus  `json:"status" gorm:"type:text"`
+	Attempts      int           `json:"attempts"`
+	NextAttemptAt time.Time     `json:"nextAttemptAt"`
+	LastError     string        `json:"lastError,omitempty"`
+	DeliveredAt   *time.Time    `json:"deliveredAt,omitempty"`
 }
 
 type UserRole string
@@ -179,100 +321,93 @@ type NewViolationRequest struct {
 	DriverID  DriverRef `json:"driverId"`
 }
 
-type DriverRef struct {
-	ID string `json:"id"`
-}
-
-type LoginReq struct {
-	Email    string `json:"email"`
-	Password string `json:"password"`
+// CreateViolationRequest je telo POST /violations. FineAmount je opcion;
+// bez njega se izdaje minimalna kazna iz tarife.
+type CreateViolationRequest struct {
+	Violation
+	FineAmount *float64 `json:"fineAmount,omitempty"`
 }
 
-type LoginResp struct {
-	AccessToken string `json:"access_token"`
-	ExpiresIn   int64  `json:"expires_in"`
-	TokenType   string `json:"token_type"`
+// PaymentSlip su podaci za nalog za uplatu kazne.
+type PaymentSlip struct {
+	FineID      string  `json:"fineId"`
+	Recipient   string  `json:"recipient"`
+	Account     string  `json:"account"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+	PaymentCode string  `json:"paymentCode"`
+	Purpose     string  `json:"purpose"`
+	Model       string  `json:"model"`
+	Reference   string  `json:"reference"`
 }
 
-//
-// ===== MUP inter-service DTOs =====
-//
+// Ishodi stavke izvoda pri uparivanju uplata.
+const (
+	PaymentMatched     = "MATCHED"
+	PaymentOverpaid    = "OVERPAID"
+	PaymentUnderpaid   = "UNDERPAID"
+	PaymentAlreadyPaid = "ALREADY_PAID"
+	PaymentCancelled   = "CANCELLED"
+	PaymentUnmatched   = "UNMATCHED"
+	PaymentInvalid     = "INVALID"
+)
 
-type MupOwner struct {
-	ID        string `json:"id"`
-	FirstName string `json:"firstName"`
-	LastName  string `json:"lastName"`
-	JMBG      string `json:"jmbg"`
-	Email     string `json:"email"`
-	Address   string `json:"address"`
+// StatementEntry je jedna stavka bankovnog izvoda i ishod uparivanja.
+type StatementEntry struct {
+	Line      int     `json:"line"`
+	Reference string  `json:"reference"`
+	Amount    float64 `json:"amount"`
+	FineID    string  `json:"fineId,omitempty"`
+	Due       float64 `json:"due,omitempty"`
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
 }
 
-type MupVehicle struct {
-	ID           string   `json:"id"`
-	Registration string   `json:"registration"`
-	Mark         string   `json:"mark"`
-	Model        string   `json:"model"`
-	Year         int      `json:"year"`
-	Color        string   `json:"color"`
-	IsStolen     bool     `json:"isStolen"`
-	Owner        MupOwner `json:"owner"`
+// ReconciliationReport je rezultat uvoza izvoda. Kazne iz Matched i
+// Overpaid su oznacene kao placene; Overpaid sadrzi i uplate za vec placene
+// (ALREADY_PAID) i ponistene (CANCELLED) kazne, koje treba vratiti.
+type ReconciliationReport struct {
+	Matched   []StatementEntry `json:"matched"`
+	Overpaid  []StatementEntry `json:"overpaid"`
+	Underpaid []StatementEntry `json:"underpaid"`
+	Unmatched []StatementEntry `json:"unmatched"`
+	Invalid   []StatementEntry `json:"invalid"`
 }
 
-type MupDriver struct {
-	ID                      string   `json:"id"`
-	IsSuspended             bool     `json:"isSuspended"`
-	NumberOfViolationPoints int      `json:"numberOfViolationPoints"`
-	Picture                 string   `json:"picture"`
-	Owner                   MupOwner `json:"owner"`
+// VoidViolationRequest: razlog ponistavanja je obavezan.
+type VoidViolationRequest struct {
+	Reason string `json:"reason"`
 }
 
-//
-// ===== MUP HTTP helpers =====
-//
-
-func MupGet[T any](client *http.Client, baseURL, path string) (*T, int, error) {
-	req, err := http.NewRequest("GET", baseURL+path, nil)
-	if err != nil {
-		return nil, 0, err
-	}
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, 0, err
-	}
-	defer res.Body.Close()
-
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		return nil, res.StatusCode, nil
-	}
+type AppealRequest struct {
+	Reason      string             `json:"reason"`
+	Attachments []AppealAttachment `json:"attachments"`
+}
 
-	var out T
-	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
-		return nil, res.StatusCode, err
-	}
-	return &out, res.StatusCode, nil
+// AppealDecisionRequest: accept=true ponistava prekrsaj.
+type AppealDecisionRequest struct {
+	Accept bool   `json:"accept"`
+	Note   string `json:"note"`
 }
 
-func MupPatchJSON[T any](client *http.Client, baseURL, path string, body any) (*T, int, error) {
-	b, _ := json.Marshal(body)
-	req, err := http.NewRequest("PATCH", baseURL+path, bytes.NewReader(b))
-	if err != nil {
-		return nil, 0, err
-	}
-	req.Header.Set("Content-Type", "application/json")
+// FineBalance je zbir neplacenih kazni vozaca.
+type FineBalance struct {
+	DriverID    string  `json:"driverId"`
+	Outstanding float64 `json:"outstanding"`
+	UnpaidCount int64   `json:"unpaidCount"`
+}
 
-	res, err := client.Do(req)
-	if err != nil {
-		return nil, 0, err
-	}
-	defer res.Body.Close()
+type DriverRef struct {
+	ID string `json:"id"`
+}
 
-	if res.StatusCode < 200 || res.StatusCode >= 300 {
-		return nil, res.StatusCode, nil
-	}
+type LoginReq struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
 
-	var out T
-	if err := json.NewDecoder(res.Body).Decode(&out); err != nil {
-		return nil, res.StatusCode, err
-	}
-	return &out, res.StatusCode, nil
+type LoginResp struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int64  `json:"expires_in"`
+	TokenType   string `json:"token_type"`
 }
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"common/jwtauth"
+	"common/policy"
+	"traffic-police/models"
+	"traffic-police/service"
+)
+
+// routePolicy je tabela pristupa za sve rute servisa.
+func routePolicy(store *service.Store) policy.Table {
+	const (
+		citizen = jwtauth.RoleCitizen
+		mup     = jwtauth.RoleMup
+		traffic = jwtauth.RoleTraffic
+		svc     = jwtauth.RoleService
+	)
+	high := service.RankLevel(models.RankHigh)
+
+	ownViolation := func(c *gin.Context, claims *jwtauth.Claims) (bool, error) {
+		var v models.Violation
+		if err := store.GetViolation(c.Param("id"), &v); err != nil {
+			return false, lookupErr(err)
+		}
+		return claims.DriverID != "" && claims.DriverID == v.DriverID, nil
+	}
+
+	ownFine := func(c *gin.Context, claims *jwtauth.Claims) (bool, error) {
+		var f models.Fine
+		if err := store.GetFine(c.Param("id"), &f); err != nil {
+			return false, lookupErr(err)
+		}
+		var v models.Violation
+		if err := store.GetViolation(f.ViolationID, &v); err != nil {
+			return false, lookupErr(err)
+		}
+		return claims.DriverID != "" && claims.DriverID == v.DriverID, nil
+	}
+
+	ownAppeal := func(c *gin.Context, claims *jwtauth.Claims) (bool, error) {
+		var a models.Appeal
+		if err := store.GetAppeal(c.Param("id"), &a); err != nil {
+			return false, lookupErr(err)
+		}
+		return claims.DriverID != "" && claims.DriverID == a.DriverID, nil
+	}
+
+	return policy.Table{
+		"GET /health": policy.Public,
+
+		// Police
+		"GET /police":                      policy.Allow(mup, traffic),
+		"POST /police":                     policy.Allow(mup, traffic).WithMinRank(high),
+		"PATCH /police/:id/toggle-suspend": policy.Allow(mup, traffic).WithMinRank(high),
+		"PATCH /police/:id/upgrade-rank":   policy.Allow(mup, traffic).WithMinRank(high),
+		"PATCH /police/:id/downgrade-rank": policy.Allow(mup, traffic).WithMinRank(high),
+		"POST /vehicles/verify":            policy.Allow(mup, traffic, svc),
+		"GET /drivers/:id/report":          policy.Allow(mup, traffic),
+		"GET /drivers/:id/suspensions":     policy.Allow(mup, traffic),
+
+		// Owners / Vehicles
+		"GET /owners":           policy.Allow(mup, traffic),
+		"GET /owners/:id":       policy.Allow(mup, traffic),
+		"POST /owners":          policy.Allow(mup, traffic),
+		"GET /vehicles":         policy.Allow(mup, traffic),
+		"GET /vehicles/:id":     policy.Allow(mup, traffic),
+		"POST /vehicles":        policy.Allow(mup, traffic),
+		"POST /vehicles/search": policy.Allow(mup, traffic),
+
+		// Violations
+		"POST /violations":                 policy.Allow(traffic),
+		"GET /violations":                  policy.Allow(mup, traffic),
+		"GET /violations/:id":              policy.Allow(mup, traffic).OrSelf(ownViolation, citizen),
+		"GET /violations/driver/:driverId": policy.Allow(mup, traffic),
+		"PATCH /violations/:id/void":       policy.Allow(mup, traffic),
+
+		// Appeals: gradjanin podnosi, odlucuje policajac najviseg cina
+		"POST /violations/:id/appeals": policy.Allow().OrSelf(ownViolation, citizen),
+		"GET /appeals":                 policy.Allow(mup, traffic),
+		"GET /appeals/:id":             policy.Allow(mup, traffic).OrSelf(ownAppeal, citizen),
+		"PATCH /appeals/:id/review":    policy.Allow(traffic).WithMinRank(high),
+		"PATCH /appeals/:id/decision":  policy.Allow(traffic).WithMinRank(high),
+
+		// Tariffs
+		"GET /tariffs":  policy.Allow(mup, traffic),
+		"POST /tariffs": policy.Allow(mup),
+
+		// Fines
+		"GET /fines/driver/:driverId":         policy.Allow(mup, traffic),
+		"GET /fines/driver/:driverId/balance": policy.Allow(mup, traffic),
+		"GET /fines/violation/:violationId":   policy.Allow(mup, traffic),
+		"PATCH /fines/:id/pay":                policy.Allow(mup, traffic),
+		"GET /fines/:id":                      policy.Allow(mup, traffic).OrSelf(ownFine, citizen),
+		"GET /fines/:id/history":              policy.Allow(mup, traffic).OrSelf(ownFine, citizen),
+		"GET /fines/:id/payment-slip":         policy.Allow(mup, traffic).OrSelf(ownFine, citizen),
+		"POST /fines/payments/import":         policy.Allow(mup),
+
+		// Gradjanin vidi samo svoje; ID vozacke dolazi iz tokena (driver_id claim)
+		"GET /me/violations":    policy.Allow(citizen),
+		"GET /me/fines":         policy.Allow(citizen),
+		"GET /me/fines/balance": policy.Allow(citizen),
+		"GET /me/appeals":       policy.Allow(citizen),
+
+		// Dijagnostika veze sa MUP-om (isporuka poena, otpornost poziva, kopija registra)
+		"GET /outbox/stuck":   policy.Allow(mup),
+		"GET /mup/stats":      policy.Allow(mup),
+		"GET /registry/sync":  policy.Allow(mup, traffic),
+		"POST /registry/sync": policy.Allow(mup),
+	}
+}
+
+// policeRank cita trenutni cin iz baze, da bi unapredjenje/degradacija
+// vazili odmah, bez cekanja na novi token.
+func policeRank(store *service.Store) policy.RankFunc {
+	return func(c *gin.Context, claims *jwtauth.Claims) (int, error) {
+		var u models.User
+		if err := store.GetPolice(claims.ID, &u); err != nil {
+			return 0, err
+		}
+		if u.PoliceProfile == nil || u.PoliceProfile.IsSuspended {
+			return 0, nil
+		}
+		return service.RankLevel(u.PoliceProfile.Rank), nil
+	}
+}
+
+// lookupErr: nepostojeci zapis nije zapis pozivaoca (403), a ostale greske
+// baze se prosledjuju da bi Enforce odgovorio sa 500.
+func lookupErr(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	return err
+}
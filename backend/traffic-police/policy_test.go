@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"common/dbtest"
+	"common/jwtauth"
+	"common/policy/policytest"
+	"traffic-police/models"
+	"traffic-police/service"
+)
+
+// newPolicyStore pravi store sa zapisima koje proveravaju OrSelf pravila i
+// cin: prekrsaj V1, kazna F1 i zalba A1 vozaca D1, i policajci razlicitog cina.
+func newPolicyStore(t *testing.T) *service.Store {
+	t.Helper()
+	db := dbtest.Open(t, "", &models.User{}, &models.Violation{}, &models.Fine{}, &models.Appeal{})
+
+	officer := func(id string, rank models.Rank, suspended bool) *models.User {
+		return &models.User{
+			BaseModel:     models.BaseModel{ID: id},
+			Email:         id + "@police.test",
+			Role:          models.UserRoleTraffic,
+			PoliceProfile: &models.PoliceProfile{Rank: rank, IsSuspended: suspended},
+		}
+	}
+	dbtest.Insert(t, db,
+		officer("high", models.RankHigh, false),
+		officer("low", models.RankLow, false),
+		officer("suspended", models.RankHigh, true),
+		&models.Violation{BaseModel: models.BaseModel{ID: "V1"}, DriverID: "D1"},
+		&models.Fine{BaseModel: models.BaseModel{ID: "F1"}, ViolationID: "V1", PaymentReference: "950000000001"},
+		&models.Appeal{BaseModel: models.BaseModel{ID: "A1"}, ViolationID: "V1", DriverID: "D1"},
+	)
+	return service.NewStore(db)
+}
+
+func TestRoutePolicy(t *testing.T) {
+	store := newPolicyStore(t)
+	routes := routePolicy(store)
+	r := policytest.New(t, routes, policeRank(store))
+
+	// bez tokena prolazi samo ono sto je javno
+	for k, rule := range routes {
+		method, path, _ := strings.Cut(k, " ")
+		want := 401
+		if rule.Public {
+			want = 200
+		}
+		if got := r.Do(method, path, ""); got != want {
+			t.Errorf("anonymous %s = %d, want %d", k, got, want)
+		}
+	}
+
+	driver := r.Token(jwtauth.Claims{Role: jwtauth.RoleCitizen, ID: "u1", DriverID: "D1"})
+	otherDriver := r.Token(jwtauth.Claims{Role: jwtauth.RoleCitizen, ID: "u2", DriverID: "D2"})
+	unlinked := r.Token(jwtauth.Claims{Role: jwtauth.RoleCitizen, ID: "u3"})
+	mup := r.Token(jwtauth.Claims{Role: jwtauth.RoleMup, ID: "m1"})
+	high := r.Token(jwtauth.Claims{Role: jwtauth.RoleTraffic, ID: "high"})
+	low := r.Token(jwtauth.Claims{Role: jwtauth.RoleTraffic, ID: "low"})
+	suspended := r.Token(jwtauth.Claims{Role: jwtauth.RoleTraffic, ID: "suspended"})
+	svc := r.Token(jwtauth.Claims{Role: jwtauth.RoleService, ID: "auth"})
+
+	tests := []struct {
+		name         string
+		method, path string
+		token        string
+		want         int
+	}{
+		// OrSelf: gradjanin vidi samo zapise svoje vozacke
+		{"own violation", "GET", "/violations/V1", driver, 200},
+		{"other driver's violation", "GET", "/violations/V1", otherDriver, 403},
+		{"citizen without driver", "GET", "/violations/V1", unlinked, 403},
+		{"unknown violation", "GET", "/violations/V404", driver, 403},
+		{"own fine via its violation", "GET", "/fines/F1/payment-slip", driver, 200},
+		{"other driver's fine", "GET", "/fines/F1", otherDriver, 403},
+		{"own appeal", "GET", "/appeals/A1", driver, 200},
+		{"other driver's appeal", "GET", "/appeals/A1", otherDriver, 403},
+		{"appeal on own violation", "POST", "/violations/V1/appeals", driver, 200},
+		{"officer cannot appeal", "POST", "/violations/V1/appeals", high, 403},
+		{"citizen list of all violations", "GET", "/violations", driver, 403},
+
+		// cin se cita iz baze
+		{"high rank reviews appeal", "PATCH", "/appeals/A1/decision", high, 200},
+		{"low rank reviews appeal", "PATCH", "/appeals/A1/decision", low, 403},
+		{"suspended officer reviews appeal", "PATCH", "/appeals/A1/review", suspended, 403},
+		{"MUP cannot review appeal", "PATCH", "/appeals/A1/review", mup, 403},
+		{"high rank adds officer", "POST", "/police", high, 200},
+		{"low rank adds officer", "POST", "/police", low, 403},
+		{"MUP adds officer without rank", "POST", "/police", mup, 200},
+
+		// SERVICE token sme samo proveru vozila
+		{"service verifies vehicle", "POST", "/vehicles/verify", svc, 200},
+		{"service lists violations", "GET", "/violations", svc, 403},
+		{"service issues violation", "POST", "/violations", svc, 403},
+		{"service reads own endpoints", "GET", "/me/fines", svc, 403},
+		{"MUP reads own endpoints", "GET", "/me/violations", mup, 403},
+		{"MUP issues violation", "POST", "/violations", mup, 403},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := r.Do(tt.method, tt.path, tt.token); got != tt.want {
+				t.Errorf("%s %s = %d, want %d", tt.method, tt.path, got, tt.want)
+			}
+		})
+	}
+
+	// greska baze nije zabrana
+	if err := store.DB.Migrator().DropTable(&models.Appeal{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.Do("GET", "/appeals/A1", driver); got != 500 {
+		t.Errorf("appeal lookup failure = %d, want 500", got)
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"traffic-police/models"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrAppealOpen   = errors.New("violation already has an open appeal")
+	ErrAppealState  = errors.New("appeal is not in a state that allows this action")
+	ErrAppealReason = errors.New("appeal reason is required")
+	// ErrAppealReviewer: zalbe razmatra samo aktivan policajac najviseg cina.
+	ErrAppealReviewer = errors.New("appeals can only be reviewed by an active HIGH-rank officer")
+)
+
+// CreateAppeal podnosi zalbu na aktivan prekrsaj; po prekrsaju moze biti
+// samo jedna otvorena zalba.
+func (s *Store) CreateAppeal(violationID, submittedBy string, req models.AppealRequest, out *models.Appeal) error {
+	if strings.TrimSpace(req.Reason) == "" {
+		return ErrAppealReason
+	}
+
+	var v models.Violation
+	if err := s.GetViolation(violationID, &v); err != nil {
+		return err
+	}
+	if v.Status == models.ViolationVoided {
+		return ErrViolationVoided
+	}
+
+	*out = models.Appeal{
+		ViolationID: v.ID,
+		DriverID:    v.DriverID,
+		SubmittedBy: submittedBy,
+		Reason:      req.Reason,
+		Attachments: req.Attachments,
+		Status:      models.AppealSubmitted,
+	}
+	if out.Attachments == nil {
+		out.Attachments = []models.AppealAttachment{}
+	}
+	if err := s.DB.Create(out).Error; err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+			return ErrAppealOpen
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Store) GetAppeal(id string, out *models.Appeal) error {
+	return s.DB.First(out, "id = ?", id).Error
+}
+
+// ListAppeals vraca zalbe, opciono samo one sa datim statusom.
+func (s *Store) ListAppeals(status models.AppealStatus, out *[]models.Appeal) error {
+	q := s.DB.Order("created_at")
+	if status != "" {
+		q = q.Where("status = ?", status)
+	}
+	return q.Find(out).Error
+}
+
+func (s *Store) ListAppealsByDriver(driverID string, out *[]models.Appeal) error {
+	return s.DB.Where("driver_id = ?", driverID).Order("created_at desc").Find(out).Error
+}
+
+// checkReviewer proverava cin iz baze i kad ruta vec ima pravilo, jer
+// prihvacena zalba ponistava prekrsaj i vraca poene.
+func checkReviewer(tx *gorm.DB, reviewerID string) error {
+	var u models.User
+	err := tx.Where("id = ? AND role = ?", reviewerID, models.UserRoleTraffic).First(&u).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return ErrAppealReviewer
+	}
+	if err != nil {
+		return err
+	}
+	p := u.PoliceProfile
+	if p == nil || p.IsSuspended || RankLevel(p.Rank) < RankLevel(models.RankHigh) {
+		return ErrAppealReviewer
+	}
+	return nil
+}
+
+// StartAppealReview preuzima podnetu zalbu na razmatranje.
+func (s *Store) StartAppealReview(id, reviewerID string, out *models.Appeal) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkReviewer(tx, reviewerID); err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(out, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if out.Status != models.AppealSubmitted {
+			return ErrAppealState
+		}
+		out.Status, out.ReviewerID = models.AppealUnderReview, reviewerID
+		return tx.Model(out).Select("Status", "ReviewerID").Updates(out).Error
+	})
+}
+
+// DecideAppeal prihvata ili odbija otvorenu zalbu. Prihvatanje u istoj
+// transakciji ponistava prekrsaj i otkazuje kaznu; voided tada sadrzi
+// ponisteni prekrsaj (poene u MUP-u vraca pozivalac).
+func (s *Store) DecideAppeal(id, reviewerID string, req models.AppealDecisionRequest, out *models.Appeal, voided *models.Violation) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := checkReviewer(tx, reviewerID); err != nil {
+			return err
+		}
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(out, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if out.Status != models.AppealSubmitted && out.Status != models.AppealUnderReview {
+			return ErrAppealState
+		}
+
+		now := time.Now()
+		out.Status = models.AppealRejected
+		if req.Accept {
+			out.Status = models.AppealAccepted
+		}
+		out.ReviewerID, out.Decision, out.ReviewedAt = reviewerID, req.Note, &now
+		if err := tx.Model(out).Select("Status", "ReviewerID", "Decision", "ReviewedAt").Updates(out).Error; err != nil {
+			return err
+		}
+		if !req.Accept {
+			return nil
+		}
+
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			First(voided, "id = ?", out.ViolationID).Error; err != nil {
+			return err
+		}
+		reason := "appeal " + out.ID + " accepted"
+		if req.Note != "" {
+			reason += ": " + req.Note
+		}
+		return voidViolation(tx, voided, reviewerID, reason, now)
+	})
+}
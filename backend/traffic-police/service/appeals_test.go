@@ -0,0 +1,53 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"common/dbtest"
+	"traffic-police/models"
+)
+
+func TestAppealReviewRequiresHighRank(t *testing.T) {
+	db := dbtest.Open(t, "", &models.User{}, &models.Violation{}, &models.Appeal{})
+	s := NewStore(db)
+
+	officer := func(id string, rank models.Rank, suspended bool) *models.User {
+		return &models.User{
+			BaseModel:     models.BaseModel{ID: id},
+			Email:         id + "@police.test",
+			Role:          models.UserRoleTraffic,
+			PoliceProfile: &models.PoliceProfile{Rank: rank, IsSuspended: suspended},
+		}
+	}
+	dbtest.Insert(t, db,
+		officer("high", models.RankHigh, false),
+		officer("low", models.RankLow, false),
+		officer("suspended", models.RankHigh, true),
+		&models.User{BaseModel: models.BaseModel{ID: "citizen"}, Email: "c@test.rs", Role: models.UserRoleCitizen},
+		&models.Violation{BaseModel: models.BaseModel{ID: "V1"}, DriverID: "D1"},
+		&models.Appeal{BaseModel: models.BaseModel{ID: "A1"}, ViolationID: "V1", DriverID: "D1", Status: models.AppealSubmitted},
+	)
+
+	var a models.Appeal
+	for _, id := range []string{"low", "suspended", "citizen", "unknown"} {
+		if err := s.StartAppealReview("A1", id, &a); !errors.Is(err, ErrAppealReviewer) {
+			t.Errorf("review by %s: err = %v, want ErrAppealReviewer", id, err)
+		}
+		var v models.Violation
+		if err := s.DecideAppeal("A1", id, models.AppealDecisionRequest{Accept: true}, &a, &v); !errors.Is(err, ErrAppealReviewer) {
+			t.Errorf("decision by %s: err = %v, want ErrAppealReviewer", id, err)
+		}
+	}
+	if err := s.GetAppeal("A1", &a); err != nil || a.Status != models.AppealSubmitted {
+		t.Fatalf("appeal = %s, %v; want untouched SUBMITTED", a.Status, err)
+	}
+
+	if err := s.StartAppealReview("A1", "high", &a); err != nil || a.Status != models.AppealUnderReview {
+		t.Fatalf("review by high = %s, %v", a.Status, err)
+	}
+	var v models.Violation
+	if err := s.DecideAppeal("A1", "high", models.AppealDecisionRequest{Note: "neosnovano"}, &a, &v); err != nil || a.Status != models.AppealRejected {
+		t.Fatalf("decision by high = %s, %v", a.Status, err)
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"time"
+
+	"traffic-police/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Rokovi i uvecanja kazni. Placanje u roku od DiscountDays dana od
+// prekrsaja umanjuje kaznu za pola; posle dospeca (DueDays) iznos raste za
+// SurchargeRate po zapocetom SurchargePeriod, najvise do MaxSurcharge.
+const (
+	DiscountDays         = 8
+	DueDays              = 30
+	EarlyPaymentDiscount = 0.5
+	SurchargeRate        = 0.10
+	SurchargePeriod      = 30 * 24 * time.Hour
+	MaxSurcharge         = 1.0
+)
+
+// newFine pravi kaznu sa rokovima racunatim od dana prekrsaja.
+func newFine(amount float64, date time.Time, violationID string) models.Fine {
+	return models.Fine{
+		Amount:        amount,
+		Date:          date,
+		ViolationID:   violationID,
+		Status:        models.FineIssued,
+		DiscountUntil: date.AddDate(0, 0, DiscountDays),
+		DueDate:       date.AddDate(0, 0, DueDays),
+	}
+}
+
+// PayableAmount je iznos koji je za kaznu potrebno uplatiti u trenutku at.
+func PayableAmount(f *models.Fine, at time.Time) float64 {
+	amount := f.Amount
+	switch {
+	case !at.After(f.DiscountUntil):
+		amount *= 1 - EarlyPaymentDiscount
+	case at.After(f.DueDate):
+		periods := math.Ceil(float64(at.Sub(f.DueDate)) / float64(SurchargePeriod))
+		amount *= 1 + math.Min(periods*SurchargeRate, MaxSurcharge)
+	}
+	return math.Round(amount*100) / 100
+}
+
+// withPayable popunjava PayableAmount za neplacene kazne.
+func withPayable(at time.Time, fines ...*models.Fine) {
+	for _, f := range fines {
+		if f.IsPaid || f.Status == models.FineCancelled {
+			f.PayableAmount = 0
+			continue
+		}
+		f.PayableAmount = PayableAmount(f, at)
+	}
+}
+
+// recordStatus upisuje prelaz u istoriju statusa kazne.
+func recordStatus(tx *gorm.DB, fineID string, from, to models.FineStatus, at time.Time, reason string) error {
+	return tx.Create(&models.FineStatusChange{
+		FineID:     fineID,
+		FromStatus: from,
+		ToStatus:   to,
+		ChangedAt:  at,
+		Reason:     reason,
+	}).Error
+}
+
+// changeStatus menja status kazne i belezi prelaz u istoriji.
+func changeStatus(tx *gorm.DB, f *models.Fine, to models.FineStatus, at time.Time, reason string) error {
+	from := f.Status
+	if err := tx.Model(f).Update("status", to).Error; err != nil {
+		return err
+	}
+	f.Status = to
+	return recordStatus(tx, f.ID, from, to, at, reason)
+}
+
+// markPaid oznacava zakljucanu kaznu placenom uplatom paid na dan at.
+func markPaid(tx *gorm.DB, f *models.Fine, paid float64, at time.Time, reason string) error {
+	f.IsPaid, f.PaidAt, f.PaidAmount = true, &at, &paid
+	if err := tx.Model(f).Select("IsPaid", "PaidAt", "PaidAmount").Updates(f).Error; err != nil {
+		return err
+	}
+	return changeStatus(tx, f, models.FinePaid, at, reason)
+}
+
+func (s *Store) FineHistory(id string, out *[]models.FineStatusChange) error {
+	return s.DB.Where("fine_id = ?", id).Order("changed_at, created_at").Find(out).Error
+}
+
+// MarkOverdueFines prebacuje u OVERDUE sve izdate kazne kojima je prosao
+// rok i vraca njihov broj.
+func (s *Store) MarkOverdueFines(now time.Time) (int, error) {
+	n := 0
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		var due []models.Fine
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND due_date < ?", models.FineIssued, now).
+			Find(&due).Error; err != nil {
+			return err
+		}
+		for i := range due {
+			if err := changeStatus(tx, &due[i], models.FineOverdue, now, "due date passed"); err != nil {
+				return err
+			}
+		}
+		n = len(due)
+		return nil
+	})
+	return n, err
+}
+
+// RunOverdueJob periodicno poziva MarkOverdueFines dok se ctx ne otkaze.
+func (s *Store) RunOverdueJob(ctx context.Context, every time.Duration) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		if n, err := s.MarkOverdueFines(time.Now()); err != nil {
+			log.Printf("overdue fines job: %v", err)
+		} else if n > 0 {
+			log.Printf("overdue fines job: %d fine(s) marked OVERDUE", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
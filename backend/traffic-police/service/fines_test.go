@@ -0,0 +1,56 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"traffic-police/models"
+)
+
+func TestPayableAmount(t *testing.T) {
+	day := 24 * time.Hour
+	date := time.Date(2026, 1, 10, 9, 0, 0, 0, time.UTC)
+	f := newFine(10000, date, "V1")
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want float64
+	}{
+		{"day of violation", date, 5000},
+		{"last discount moment", f.DiscountUntil, 5000},
+		{"after discount", f.DiscountUntil.Add(time.Second), 10000},
+		{"on due date", f.DueDate, 10000},
+		{"first surcharge period started", f.DueDate.Add(time.Hour), 11000},
+		{"end of first period", f.DueDate.Add(SurchargePeriod), 11000},
+		{"second period started", f.DueDate.Add(SurchargePeriod + time.Second), 12000},
+		{"surcharge capped", f.DueDate.Add(3000 * day), 20000},
+	}
+	for _, tt := range tests {
+		if got := PayableAmount(&f, tt.at); got != tt.want {
+			t.Errorf("%s: PayableAmount = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPayableAmountRoundsToPara(t *testing.T) {
+	date := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	f := newFine(333.33, date, "V1")
+	if got := PayableAmount(&f, date); got != 166.67 {
+		t.Fatalf("PayableAmount = %v, want 166.67", got)
+	}
+}
+
+func TestWithPayableSkipsClosedFines(t *testing.T) {
+	date := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	open := newFine(1000, date, "V1")
+	paid := newFine(1000, date, "V2")
+	paid.IsPaid, paid.PayableAmount = true, 123
+	cancelled := newFine(1000, date, "V3")
+	cancelled.Status = models.FineCancelled
+
+	withPayable(date, &open, &paid, &cancelled)
+	if open.PayableAmount != 500 || paid.PayableAmount != 0 || cancelled.PayableAmount != 0 {
+		t.Fatalf("payable = %v/%v/%v, want 500/0/0", open.PayableAmount, paid.PayableAmount, cancelled.PayableAmount)
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"traffic-police/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Isporuka outbox poruka: posle svakog neuspeha sledeci pokusaj se odlaze
+// duplo duze (od OutboxBaseBackoff do OutboxMaxBackoff), bez odustajanja.
+// Poruka sa bar OutboxStuckAttempts neuspeha se smatra zaglavljenom, a
+// poruka u slanju je zakupljena na OutboxLease.
+const (
+	OutboxBatchSize     = 20
+	OutboxLease         = time.Minute
+	OutboxBaseBackoff   = 5 * time.Second
+	OutboxMaxBackoff    = time.Hour
+	OutboxStuckAttempts = 5
+)
+
+// OutboxDeliverer salje poruku; nil znaci da je primalac potvrdio prijem.
+type OutboxDeliverer func(ctx context.Context, m *models.OutboxMessage) error
+
+// enqueuePoints upisuje promenu poena vozaca u outbox transakcije tx.
+func enqueuePoints(tx *gorm.DB, v *models.Violation, delta int) error {
+	if delta == 0 {
+		return nil
+	}
+	return tx.Create(&models.OutboxMessage{
+		Topic:       models.OutboxDriverPoints,
+		AggregateID: v.ID,
+		Payload: models.PointsPayload{
+			DriverID:    v.DriverID,
+			ViolationID: v.ID,
+			Delta:       delta,
+			Date:        v.Date,
+		},
+		Status:        models.OutboxPending,
+		NextAttemptAt: time.Now(),
+	}).Error
+}
+
+// outboxBackoff je pauza posle attempts neuspelih pokusaja.
+func outboxBackoff(attempts int) time.Duration {
+	d := OutboxBaseBackoff
+	for i := 1; i < attempts && d < OutboxMaxBackoff; i++ {
+		d *= 2
+	}
+	return min(d, OutboxMaxBackoff)
+}
+
+// markDelivered zatvara poruku m preuzetu u lq. Za vracanje poena belezi se
+// i na prekrsaju.
+func markDelivered(tx *gorm.DB, lq *gorm.DB, m *models.OutboxMessage, at time.Time) error {
+	res := lq.Updates(map[string]any{
+		"status":       models.OutboxDelivered,
+		"attempts":     m.Attempts + 1,
+		"delivered_at": at,
+		"last_error":   "",
+	})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return res.Error
+	}
+	if m.Topic == models.OutboxDriverPoints && m.Payload.Delta < 0 {
+		return tx.Model(&models.Violation{}).
+			Where("id = ?", m.Payload.ViolationID).
+			Update("points_reversed", true).Error
+	}
+	return nil
+}
+
+// claimOutbox u kratkoj transakciji preuzima do OutboxBatchSize poruka koje
+// vrati scope: next_attempt_at im se pomera na kraj zakupa (OutboxLease), pa
+// ih ni druga instanca ni sledeci krug nece uzeti dok traje slanje. Uzima se
+// samo najstarija poruka agregata na cekanju, da bi isporuka isla redom.
+func (s *Store) claimOutbox(ctx context.Context, now time.Time, scope func(*gorm.DB) *gorm.DB) ([]models.OutboxMessage, error) {
+	var batch []models.OutboxMessage
+	lease := time.Now().Add(OutboxLease).Truncate(time.Microsecond)
+	err := s.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, now).
+			Where(`NOT EXISTS (SELECT 1 FROM outbox_messages o
+				WHERE o.aggregate_id = outbox_messages.aggregate_id AND o.status = ?
+				AND (o.created_at, o.id) < (outbox_messages.created_at, outbox_messages.id))`, models.OutboxPending)
+		if err := scope(q).Order("created_at, id").Limit(OutboxBatchSize).Find(&batch).Error; err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(batch))
+		for i := range batch {
+			ids[i] = batch[i].ID
+		}
+		return tx.Model(&models.OutboxMessage{}).Where("id IN ?", ids).
+			Update("next_attempt_at", lease).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range batch {
+		batch[i].NextAttemptAt = lease
+	}
+	return batch, nil
+}
+
+// finishOutbox belezi ishod slanja poruke m. Ako je zakup u medjuvremenu
+// istekao i poruku je preuzeo neko drugi, ishod se ne upisuje.
+func (s *Store) finishOutbox(m *models.OutboxMessage, derr error, at time.Time) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		lq := tx.Model(m).Where("status = ? AND next_attempt_at = ?", models.OutboxPending, m.NextAttemptAt)
+		if derr == nil {
+			return markDelivered(tx, lq, m, at)
+		}
+		return lq.Updates(map[string]any{
+			"attempts":        m.Attempts + 1,
+			"last_error":      derr.Error(),
+			"next_attempt_at": at.Add(outboxBackoff(m.Attempts + 1)),
+		}).Error
+	})
+}
+
+// dispatch salje poruke koje vrati scope, u krugovima dok ima poruka kojima
+// je do now dosao red. Slanje ide van transakcije; poruka koja ne uspe ceka
+// sledeci pokusaj, a sa njom i kasnije poruke istog agregata.
+func (s *Store) dispatch(ctx context.Context, now time.Time, deliver OutboxDeliverer, scope func(*gorm.DB) *gorm.DB) (sent, failed int, err error) {
+	for {
+		batch, err := s.claimOutbox(ctx, now, scope)
+		if err != nil || len(batch) == 0 {
+			return sent, failed, err
+		}
+
+		for i := range batch {
+			m := &batch[i]
+			derr := deliver(ctx, m)
+			if err := s.finishOutbox(m, derr, time.Now()); err != nil {
+				return sent, failed, err
+			}
+			if derr != nil {
+				failed++
+			} else {
+				sent++
+			}
+		}
+	}
+}
+
+// DispatchOutbox salje poruke kojima je dosao red za sledeci pokusaj.
+func (s *Store) DispatchOutbox(ctx context.Context, now time.Time, deliver OutboxDeliverer) (sent, failed int, err error) {
+	return s.dispatch(ctx, now, deliver, func(q *gorm.DB) *gorm.DB { return q })
+}
+
+// DispatchFor odmah pokusava da isporuci poruke jednog agregata (npr. tek
+// upisanog prekrsaja). Vraca true ako nista nije ostalo na cekanju.
+func (s *Store) DispatchFor(ctx context.Context, aggregateID string, deliver OutboxDeliverer) (bool, error) {
+	if _, _, err := s.dispatch(ctx, time.Now(), deliver, func(q *gorm.DB) *gorm.DB {
+		return q.Where("aggregate_id = ?", aggregateID)
+	}); err != nil {
+		return false, err
+	}
+	var pending int64
+	err := s.DB.Model(&models.OutboxMessage{}).
+		Where("aggregate_id = ? AND status = ?", aggregateID, models.OutboxPending).
+		Count(&pending).Error
+	return pending == 0, err
+}
+
+// ListStuckOutbox vraca neisporucene poruke sa bar OutboxStuckAttempts neuspeha.
+func (s *Store) ListStuckOutbox(out *[]models.OutboxMessage) error {
+	return s.DB.Where("status = ? AND attempts >= ?", models.OutboxPending, OutboxStuckAttempts).
+		Order("created_at").Find(out).Error
+}
+
+// RunOutboxDispatcher periodicno poziva DispatchOutbox dok se ctx ne otkaze.
+func (s *Store) RunOutboxDispatcher(ctx context.Context, every time.Duration, deliver OutboxDeliverer) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		if sent, failed, err := s.DispatchOutbox(ctx, time.Now(), deliver); err != nil {
+			log.Printf("outbox dispatcher: %v", err)
+		} else if sent > 0 || failed > 0 {
+			log.Printf("outbox dispatcher: %d delivered, %d failed", sent, failed)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
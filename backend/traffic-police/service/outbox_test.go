@@ -0,0 +1,143 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"common/dbtest"
+	"traffic-police/models"
+
+	"gorm.io/gorm"
+)
+
+func newOutboxStore(t *testing.T) *Store {
+	t.Helper()
+	return NewStore(dbtest.Open(t, "", &models.OutboxMessage{}, &models.Violation{}))
+}
+
+// addMessage upisuje poruku za prekrsaj violationID; redosled odredjuje created.
+func addMessage(t *testing.T, s *Store, id, violationID string, delta int, created time.Time) {
+	t.Helper()
+	if err := s.DB.Create(&models.OutboxMessage{
+		BaseModel:     models.BaseModel{ID: id, CreatedAt: created},
+		Topic:         models.OutboxDriverPoints,
+		AggregateID:   violationID,
+		Payload:       models.PointsPayload{DriverID: "D1", ViolationID: violationID, Delta: delta},
+		Status:        models.OutboxPending,
+		NextAttemptAt: created,
+	}).Error; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func outboxMessage(t *testing.T, s *Store, id string) models.OutboxMessage {
+	t.Helper()
+	var m models.OutboxMessage
+	if err := s.DB.First(&m, "id = ?", id).Error; err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestDispatchOutboxKeepsPerViolationOrder(t *testing.T) {
+	s := newOutboxStore(t)
+	if err := s.DB.Create(&models.Violation{BaseModel: models.BaseModel{ID: "V1"}, DriverID: "D1"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now().Add(-time.Minute)
+	addMessage(t, s, "m1", "V1", 3, start)
+	addMessage(t, s, "m2", "V2", 2, start.Add(time.Second))
+	addMessage(t, s, "m3", "V1", -3, start.Add(2*time.Second))
+
+	var delivered []string
+	failV1 := true
+	deliver := func(_ context.Context, m *models.OutboxMessage) error {
+		if failV1 && m.AggregateID == "V1" {
+			return errors.New("mup down")
+		}
+		delivered = append(delivered, m.ID)
+		return nil
+	}
+
+	now := time.Now()
+	sent, failed, err := s.DispatchOutbox(context.Background(), now, deliver)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sent != 1 || failed != 1 || len(delivered) != 1 || delivered[0] != "m2" {
+		t.Fatalf("sent=%d failed=%d delivered=%v; want only m2 delivered", sent, failed, delivered)
+	}
+	m1 := outboxMessage(t, s, "m1")
+	if m1.Attempts != 1 || m1.LastError != "mup down" || !m1.NextAttemptAt.After(now) {
+		t.Fatalf("failed message = %+v, want one attempt with backoff", m1)
+	}
+	// kasnija poruka istog prekrsaja ne sme da prestigne neisporucenu
+	if m3 := outboxMessage(t, s, "m3"); m3.Attempts != 0 || m3.Status != models.OutboxPending {
+		t.Fatalf("m3 = %+v, want untouched while m1 is pending", m3)
+	}
+
+	// pre isteka pauze nista se ne salje
+	if sent, failed, _ := s.DispatchOutbox(context.Background(), now, deliver); sent+failed != 0 {
+		t.Fatalf("dispatched %d message(s) during backoff", sent+failed)
+	}
+
+	failV1 = false
+	delivered = nil
+	later := now.Add(OutboxBaseBackoff + time.Second)
+	if sent, _, err = s.DispatchOutbox(context.Background(), later, deliver); err != nil || sent != 2 {
+		t.Fatalf("sent = %d, %v; want 2", sent, err)
+	}
+	if len(delivered) != 2 || delivered[0] != "m1" || delivered[1] != "m3" {
+		t.Fatalf("delivery order = %v, want [m1 m3]", delivered)
+	}
+
+	var v models.Violation
+	if err := s.DB.First(&v, "id = ?", "V1").Error; err != nil {
+		t.Fatal(err)
+	}
+	if !v.PointsReversed {
+		t.Fatal("delivered reversal did not mark the violation")
+	}
+}
+
+func TestDispatchOutboxSkipsLeasedMessages(t *testing.T) {
+	s := newOutboxStore(t)
+	start := time.Now().Add(-time.Minute)
+	addMessage(t, s, "m1", "V1", 3, start)
+
+	now := time.Now()
+	batch, err := s.claimOutbox(context.Background(), now, func(q *gorm.DB) *gorm.DB { return q })
+	if err != nil || len(batch) != 1 {
+		t.Fatalf("claim = %d message(s), %v; want 1", len(batch), err)
+	}
+	if again, _ := s.claimOutbox(context.Background(), now, func(q *gorm.DB) *gorm.DB { return q }); len(again) != 0 {
+		t.Fatal("leased message was claimed twice")
+	}
+
+	// zakup je istekao i poruku je preuzeo neko drugi: stari ishod se ne upisuje
+	if err := s.DB.Model(&models.OutboxMessage{}).Where("id = ?", "m1").
+		Update("next_attempt_at", now.Add(time.Hour)).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := s.finishOutbox(&batch[0], nil, now); err != nil {
+		t.Fatal(err)
+	}
+	if m := outboxMessage(t, s, "m1"); m.Status != models.OutboxPending {
+		t.Fatalf("status = %s, want PENDING after a lost lease", m.Status)
+	}
+}
+
+func TestOutboxBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1:  OutboxBaseBackoff,
+		2:  2 * OutboxBaseBackoff,
+		4:  8 * OutboxBaseBackoff,
+		40: OutboxMaxBackoff,
+	} {
+		if got := outboxBackoff(attempts); got != want {
+			t.Errorf("outboxBackoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+
+	"traffic-police/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PaymentModel je oznaka modela poziva na broj.
+const PaymentModel = "97"
+
+// referenceDigits je duzina rednog broja u pozivu na broj (bez kontrolnih cifara).
+const referenceDigits = 10
+
+var ErrInvalidReference = errors.New("invalid payment reference")
+
+// mod97 racuna ostatak deljenja decimalnog broja (string cifara) sa 97.
+func mod97(digits string) int {
+	n, _ := new(big.Int).SetString(digits, 10)
+	return int(new(big.Int).Mod(n, big.NewInt(97)).Int64())
+}
+
+// PaymentReference pravi poziv na broj po modelu 97 (ISO 7064, MOD 97-10):
+// kontrolni broj je 98 - (broj*100 mod 97), ispred rednog broja.
+func PaymentReference(seq int64) string {
+	base := fmt.Sprintf("%0*d", referenceDigits, seq)
+	check := 98 - mod97(base+"00")
+	return fmt.Sprintf("%02d%s", check, base)
+}
+
+// NormalizeReference uklanja oznaku modela, razmake i crtice i proverava
+// kontrolne cifre.
+func NormalizeReference(raw string) (string, error) {
+	ref := strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(raw))
+	if len(ref) == referenceDigits+4 && strings.HasPrefix(ref, PaymentModel) {
+		ref = ref[len(PaymentModel):]
+	}
+	if len(ref) != referenceDigits+2 {
+		return "", ErrInvalidReference
+	}
+	for _, r := range ref {
+		if r < '0' || r > '9' {
+			return "", ErrInvalidReference
+		}
+	}
+	if mod97(ref[2:]+ref[:2]) != 1 {
+		return "", ErrInvalidReference
+	}
+	return ref, nil
+}
+
+// assignPaymentReference dodeljuje kazni sledeci poziv na broj.
+func assignPaymentReference(tx *gorm.DB, f *models.Fine) error {
+	var seq int64
+	if err := tx.Raw("SELECT nextval('fine_payment_ref_seq')").Scan(&seq).Error; err != nil {
+		return err
+	}
+	f.PaymentReference = PaymentReference(seq)
+	return nil
+}
+
+func (s *Store) PaymentSlip(id string, recipient, account string, out *models.PaymentSlip) error {
+	var f models.Fine
+	if err := s.GetFine(id, &f); err != nil {
+		return err
+	}
+	*out = models.PaymentSlip{
+		FineID:      f.ID,
+		Recipient:   recipient,
+		Account:     account,
+		Amount:      f.PayableAmount,
+		Currency:    "RSD",
+		PaymentCode: "253",
+		Purpose:     "Novcana kazna za saobracajni prekrsaj " + f.ViolationID,
+		Model:       PaymentModel,
+		Reference:   f.PaymentReference,
+	}
+	return nil
+}
+
+// parseAmount prihvata i decimalni zarez ("3000,00").
+func parseAmount(raw string) (float64, error) {
+	raw = strings.TrimSpace(raw)
+	if !strings.Contains(raw, ".") {
+		raw = strings.Replace(raw, ",", ".", 1)
+	}
+	return strconv.ParseFloat(raw, 64)
+}
+
+func parsePaymentDate(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	for _, layout := range []string{"2006-01-02", "02.01.2006", "02.01.2006."} {
+		if t, err := time.Parse(layout, raw); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", raw)
+}
+
+var ErrStatementFormat = errors.New("invalid statement")
+
+// ReconcileStatement uvozi bankovni izvod (CSV: reference,amount[,date]) i
+// oznacava placenim kazne cija je uplata dovoljna. Prvi red moze biti zaglavlje.
+// Ceo izvod se primenjuje u jednoj transakciji: posle greske baze nijedna
+// uplata nije upisana, pa se uvoz moze ponoviti.
+func (s *Store) ReconcileStatement(r io.Reader, out *models.ReconciliationReport) error {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	report := models.ReconciliationReport{
+		Matched:   []models.StatementEntry{},
+		Overpaid:  []models.StatementEntry{},
+		Underpaid: []models.StatementEntry{},
+		Unmatched: []models.StatementEntry{},
+		Invalid:   []models.StatementEntry{},
+	}
+
+	type payment struct {
+		entry  models.StatementEntry
+		ref    string
+		paidAt time.Time
+	}
+	var payments []payment
+
+	for line := 1; ; line++ {
+		rec, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: line %d: %v", ErrStatementFormat, line, err)
+		}
+		if line == 1 && len(rec) > 0 && strings.EqualFold(strings.TrimSpace(rec[0]), "reference") {
+			continue
+		}
+
+		e := models.StatementEntry{Line: line}
+		if len(rec) < 2 {
+			e.Status, e.Error = models.PaymentInvalid, "expected reference,amount[,date]"
+			report.Invalid = append(report.Invalid, e)
+			continue
+		}
+		e.Reference = strings.TrimSpace(rec[0])
+
+		amount, err := parseAmount(rec[1])
+		if err != nil || amount <= 0 {
+			e.Status, e.Error = models.PaymentInvalid, "invalid amount"
+			report.Invalid = append(report.Invalid, e)
+			continue
+		}
+		e.Amount = amount
+
+		paidAt := time.Now()
+		if len(rec) > 2 && strings.TrimSpace(rec[2]) != "" {
+			if paidAt, err = parsePaymentDate(rec[2]); err != nil {
+				e.Status, e.Error = models.PaymentInvalid, err.Error()
+				report.Invalid = append(report.Invalid, e)
+				continue
+			}
+		}
+
+		ref, err := NormalizeReference(e.Reference)
+		if err != nil {
+			e.Status, e.Error = models.PaymentInvalid, err.Error()
+			report.Invalid = append(report.Invalid, e)
+			continue
+		}
+		payments = append(payments, payment{entry: e, ref: ref, paidAt: paidAt})
+	}
+
+	err := s.DB.Transaction(func(tx *gorm.DB) error {
+		for i := range payments {
+			p := &payments[i]
+			if err := applyPayment(tx, p.ref, p.entry.Amount, p.paidAt, &p.entry); err != nil {
+				return fmt.Errorf("line %d: %w", p.entry.Line, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range payments {
+		e := p.entry
+		switch e.Status {
+		case models.PaymentMatched:
+			report.Matched = append(report.Matched, e)
+		case models.PaymentOverpaid, models.PaymentAlreadyPaid, models.PaymentCancelled:
+			report.Overpaid = append(report.Overpaid, e)
+		case models.PaymentUnderpaid:
+			report.Underpaid = append(report.Underpaid, e)
+		default:
+			report.Unmatched = append(report.Unmatched, e)
+		}
+	}
+	*out = report
+	return nil
+}
+
+// applyPayment upari jednu uplatu sa kaznom i, ako je dovoljna, oznaci je
+// placenom u okviru tx. Ishod upisuje u e.Status.
+func applyPayment(tx *gorm.DB, ref string, amount float64, paidAt time.Time, e *models.StatementEntry) error {
+	var f models.Fine
+	res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("payment_reference = ?", ref).Limit(1).Find(&f)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		e.Status = models.PaymentUnmatched
+		return nil
+	}
+
+	e.FineID = f.ID
+	if f.Status == models.FineCancelled {
+		e.Status = models.PaymentCancelled
+		return nil
+	}
+	if f.IsPaid {
+		e.Status = models.PaymentAlreadyPaid
+		return nil
+	}
+
+	// popust/uvecanje po datumu uplate, ne po datumu uvoza
+	e.Due = PayableAmount(&f, paidAt)
+	switch {
+	case amount < e.Due:
+		e.Status = models.PaymentUnderpaid
+		return nil
+	case amount > e.Due:
+		e.Status = models.PaymentOverpaid
+	default:
+		e.Status = models.PaymentMatched
+	}
+
+	return markPaid(tx, &f, amount, paidAt, "bank statement "+ref)
+}
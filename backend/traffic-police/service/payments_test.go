@@ -0,0 +1,77 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPaymentReference(t *testing.T) {
+	if got := PaymentReference(1); got != "950000000001" {
+		t.Fatalf("PaymentReference(1) = %s, want 950000000001", got)
+	}
+
+	for _, seq := range []int64{1, 42, 1000, 9_999_999_999} {
+		ref := PaymentReference(seq)
+		if len(ref) != referenceDigits+2 {
+			t.Fatalf("PaymentReference(%d) = %s, want %d digits", seq, ref, referenceDigits+2)
+		}
+		// ISO 7064: broj sa kontrolnim ciframa na kraju daje ostatak 1
+		if mod97(ref[2:]+ref[:2]) != 1 {
+			t.Fatalf("PaymentReference(%d) = %s fails the MOD 97-10 check", seq, ref)
+		}
+		if got, err := NormalizeReference(ref); err != nil || got != ref {
+			t.Fatalf("NormalizeReference(%s) = %s, %v", ref, got, err)
+		}
+	}
+}
+
+func TestNormalizeReference(t *testing.T) {
+	valid := map[string]string{
+		"950000000001":         "950000000001",
+		" 95-0000000001 ":      "950000000001",
+		"97 95 0000000001":     "950000000001",
+		"97-95-0000-0000-01":   "950000000001",
+		PaymentReference(4711): PaymentReference(4711),
+	}
+	for raw, want := range valid {
+		if got, err := NormalizeReference(raw); err != nil || got != want {
+			t.Errorf("NormalizeReference(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+
+	for _, raw := range []string{
+		"",
+		"940000000001",  // pogresne kontrolne cifre
+		"950000000002",  // promenjen redni broj
+		"95000000001",   // prekratak
+		"9500000000012", // predugacak
+		"95000000000A",
+		"98 950000000001", // pogresan model
+	} {
+		if _, err := NormalizeReference(raw); !errors.Is(err, ErrInvalidReference) {
+			t.Errorf("NormalizeReference(%q) err = %v, want ErrInvalidReference", raw, err)
+		}
+	}
+}
+
+func TestParseStatementFields(t *testing.T) {
+	for raw, want := range map[string]float64{"5000": 5000, "5000.50": 5000.5, "5000,50": 5000.5, " 12 ": 12} {
+		if got, err := parseAmount(raw); err != nil || got != want {
+			t.Errorf("parseAmount(%q) = %v, %v; want %v", raw, got, err, want)
+		}
+	}
+	if _, err := parseAmount("pet hiljada"); err == nil {
+		t.Error("parseAmount accepted a non-number")
+	}
+
+	want := time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC)
+	for _, raw := range []string{"2026-03-07", "07.03.2026", "07.03.2026."} {
+		if got, err := parsePaymentDate(raw); err != nil || !got.Equal(want) {
+			t.Errorf("parsePaymentDate(%q) = %v, %v", raw, got, err)
+		}
+	}
+	if _, err := parsePaymentDate("7/3/2026"); err == nil {
+		t.Error("parsePaymentDate accepted an unknown layout")
+	}
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"traffic-police/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kopija MUP registra: vlasnici, vozila i vozaci iz MUP-a se upisuju pod
+// MUP ID-jevima (Source MUP). Posle punog snimka stizu samo izmene od
+// kursora; pun snimak se ponavlja posle RegistryFullSyncEvery. MUP ne brise
+// zapise, pa ni sinhronizacija ne brise.
+const (
+	registrySyncID        = "mup"
+	RegistryFullSyncEvery = 24 * time.Hour
+)
+
+// RegistryBatch je stranica punog snimka (Full) ili izmena MUP registra, vec
+// prevedena u lokalne modele. Next je prazan na poslednjoj stranici.
+type RegistryBatch struct {
+	Full     bool
+	Cursor   time.Time
+	Next     string
+	Owners   []models.Owner
+	Vehicles []models.Vehicle
+	Drivers  []models.Driver
+}
+
+// RegistryFetcher vraca stranicu izmena od since (nula trazi pun snimak);
+// page je Next prethodne stranice.
+type RegistryFetcher func(ctx context.Context, since time.Time, page string) (*RegistryBatch, error)
+
+func (s *Store) GetRegistrySync(out *models.RegistrySync) error {
+	return s.DB.Where(models.RegistrySync{ID: registrySyncID}).
+		Attrs(models.RegistrySync{ID: registrySyncID}).
+		FirstOrInit(out).Error
+}
+
+// SyncRegistry preuzima izmene registra od poslednjeg kursora (ili pun
+// snimak kad je full, kad kursora nema ili je pun snimak zastareo), stranicu
+// po stranicu, svaku u svojoj transakciji. Kursor se pomera tek posle
+// poslednje stranice; neuspeh se belezi u LastError, a kursor ostaje isti.
+func (s *Store) SyncRegistry(ctx context.Context, full bool, fetch RegistryFetcher, out *models.RegistrySync) error {
+	var st models.RegistrySync
+	if err := s.GetRegistrySync(&st); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var since time.Time
+	if !full && st.Cursor != nil && st.LastFullSyncAt != nil && now.Sub(*st.LastFullSyncAt) < RegistryFullSyncEvery {
+		since = *st.Cursor
+	}
+	st.LastAttemptAt = &now
+	st.Owners, st.Vehicles, st.Drivers = 0, 0, 0
+
+	batch, err := fetch(ctx, since, "")
+	for err == nil {
+		err = s.DB.Transaction(func(tx *gorm.DB) error {
+			return applyRegistry(tx, batch, &st, now)
+		})
+		if err != nil || batch.Next == "" {
+			break
+		}
+		batch, err = fetch(ctx, since, batch.Next)
+	}
+	if err != nil {
+		st.LastError = err.Error()
+		if serr := s.DB.Save(&st).Error; serr != nil {
+			log.Printf("registry sync: save state: %v", serr)
+		}
+		return err
+	}
+	*out = st
+	return nil
+}
+
+// applyRegistry upisuje batch u okviru tx, a posle poslednje stranice pomera
+// i kursor. Vlasnici idu prvi jer ih vozila i vozaci referenciraju.
+func applyRegistry(tx *gorm.DB, b *RegistryBatch, st *models.RegistrySync, now time.Time) error {
+	for i := range b.Owners {
+		if err := upsertOwner(tx, &b.Owners[i], now); err != nil {
+			return err
+		}
+	}
+	for i := range b.Vehicles {
+		if err := upsertVehicle(tx, &b.Vehicles[i], now); err != nil {
+			return err
+		}
+	}
+	for i := range b.Drivers {
+		d := &b.Drivers[i]
+		d.Source, d.SyncedAt = models.SourceMup, &now
+		if err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns([]string{
+				"is_suspended", "number_of_violation_points", "picture", "owner_id",
+				"source", "synced_at", "updated_at",
+			}),
+		}).Create(d).Error; err != nil {
+			return err
+		}
+	}
+
+	st.Owners += len(b.Owners)
+	st.Vehicles += len(b.Vehicles)
+	st.Drivers += len(b.Drivers)
+	if b.Next == "" {
+		cursor := b.Cursor
+		st.Cursor, st.LastSyncAt, st.LastError = &cursor, &now, ""
+		if b.Full {
+			st.LastFullSyncAt = &now
+		}
+	}
+	return tx.Save(st).Error
+}
+
+// upsertOwner upisuje MUP vlasnika. Drugi zapis sa istim JMBG-om (rucno
+// unet pre nego sto je vlasnik stigao iz MUP-a) se spaja u MUP-ov: reference
+// prelaze na MUP ID, a veza sa nalogom (user_id) se zadrzava.
+func upsertOwner(tx *gorm.DB, o *models.Owner, now time.Time) error {
+	var dup models.Owner
+	res := tx.Where("jmbg = ? AND jmbg <> '' AND id <> ?", o.JMBG, o.ID).Limit(1).Find(&dup)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		if err := tx.Model(&models.Owner{}).Where("id = ?", dup.ID).
+			Updates(map[string]any{"jmbg": nil, "user_id": ""}).Error; err != nil {
+			return err
+		}
+	}
+
+	o.Source, o.SyncedAt = models.SourceMup, &now
+	if err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"first_name", "last_name", "address", "jmbg", "email",
+			"source", "synced_at", "updated_at",
+		}),
+	}).Create(o).Error; err != nil {
+		return err
+	}
+	if res.RowsAffected == 0 {
+		return nil
+	}
+
+	for _, table := range []string{"vehicles", "drivers"} {
+		if err := tx.Table(table).Where("owner_id = ?", dup.ID).
+			Update("owner_id", o.ID).Error; err != nil {
+			return err
+		}
+	}
+	if dup.UserID != "" {
+		if err := tx.Model(&models.Owner{}).Where("id = ? AND COALESCE(user_id, '') = ''", o.ID).
+			Update("user_id", dup.UserID).Error; err != nil {
+			return err
+		}
+	}
+	return tx.Delete(&models.Owner{}, "id = ?", dup.ID).Error
+}
+
+// upsertVehicle upisuje MUP vozilo; drugi zapis sa istim tablicama se spaja
+// u MUP-ov kao kod vlasnika.
+func upsertVehicle(tx *gorm.DB, v *models.Vehicle, now time.Time) error {
+	var dup models.Vehicle
+	res := tx.Where("registration = ? AND registration <> '' AND id <> ?", v.Registration, v.ID).Limit(1).Find(&dup)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected > 0 {
+		if err := tx.Model(&models.Vehicle{}).Where("id = ?", dup.ID).
+			Update("registration", nil).Error; err != nil {
+			return err
+		}
+	}
+
+	v.Source, v.SyncedAt = models.SourceMup, &now
+	if err := tx.Omit(clause.Associations).Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"mark", "model", "registration", "year", "color", "is_stolen", "owner_id",
+			"source", "synced_at", "updated_at",
+		}),
+	}).Create(v).Error; err != nil {
+		return err
+	}
+	if res.RowsAffected == 0 {
+		return nil
+	}
+	return tx.Delete(&models.Vehicle{}, "id = ?", dup.ID).Error
+}
+
+// RunRegistrySync poziva SyncRegistry odmah i zatim na svakih every, dok se
+// ctx ne otkaze.
+func (s *Store) RunRegistrySync(ctx context.Context, every time.Duration, fetch RegistryFetcher) {
+	t := time.NewTicker(every)
+	defer t.Stop()
+	for {
+		var st models.RegistrySync
+		if err := s.SyncRegistry(ctx, false, fetch, &st); err != nil {
+			log.Printf("registry sync: %v", err)
+		} else if n := st.Owners + st.Vehicles + st.Drivers; n > 0 {
+			log.Printf("registry sync: %d record(s) updated", n)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
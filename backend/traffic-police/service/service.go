@@ -3,10 +3,13 @@ package service
 import (
 	"errors"
 	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"traffic-police/models"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -52,6 +55,16 @@ func (s *Store) TogglePoliceSuspend(id string, out *models.User) error {
 
 var rankOrder = []models.Rank{models.RankLow, models.RankMedium, models.RankHigh}
 
+// RankLevel vraca nivo cina (LOW=1, MEDIUM=2, HIGH=3), 0 za nepoznat cin.
+func RankLevel(r models.Rank) int {
+	for i, o := range rankOrder {
+		if o == r {
+			return i + 1
+		}
+	}
+	return 0
+}
+
 func (s *Store) ChangePoliceRank(id string, out *models.User, upgrade bool) error {
 	if err := s.GetPolice(id, out); err != nil {
 		return err
@@ -78,46 +91,124 @@ func (s *Store) ChangePoliceRank(id string, out *models.User, upgrade bool) erro
 }
 
 //
-// ===== Owners =====
+// ===== Owners / Vehicles =====
 //
+// MUP registar je izvor istine za vlasnike i vozila. Lokalne tabele owners i
+// vehicles su evidencija saobracajne policije: kopija MUP zapisa (Source
+// MUP) i rucno uneti zapisi koji u MUP-u ne postoje (Source LOCAL, npr.
+// vozila sa stranim tablicama). Rucno se unose samo LOCAL zapisi.
+//
+
+var (
+	ErrInvalid   = errors.New("invalid input")
+	ErrDuplicate = errors.New("already exists")
+)
 
+// Page je stranica rezultata; Number pocinje od 1.
+type Page struct {
+	Number int
+	Size   int
+}
+
+func (p Page) apply(q *gorm.DB) *gorm.DB {
+	return q.Offset((p.Number - 1) * p.Size).Limit(p.Size)
+}
+
+// duplicate pretvara krsenje jedinstvenog indeksa u ErrDuplicate.
+func duplicate(err error, what string) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" {
+		return fmt.Errorf("%w: %s", ErrDuplicate, what)
+	}
+	return err
+}
+
+func validateOwner(o *models.Owner) error {
+	o.FirstName, o.LastName = strings.TrimSpace(o.FirstName), strings.TrimSpace(o.LastName)
+	o.JMBG, o.Email = strings.TrimSpace(o.JMBG), strings.TrimSpace(strings.ToLower(o.Email))
+	switch {
+	case o.FirstName == "" || o.LastName == "":
+		return fmt.Errorf("%w: firstName and lastName are required", ErrInvalid)
+	case len(o.JMBG) != 13 || strings.Trim(o.JMBG, "0123456789") != "":
+		return fmt.Errorf("%w: jmbg must have 13 digits", ErrInvalid)
+	case o.Email != "" && !strings.Contains(o.Email, "@"):
+		return fmt.Errorf("%w: invalid email", ErrInvalid)
+	}
+	return nil
+}
+
+// CreateOwner upisuje rucno unetog (LOCAL) vlasnika.
 func (s *Store) CreateOwner(o *models.Owner) error {
-	return s.DB.Create(o).Error
+	if err := validateOwner(o); err != nil {
+		return err
+	}
+	o.ID, o.Source = "", models.SourceLocal
+	return duplicate(s.DB.Create(o).Error, "owner with this jmbg")
 }
 
-func (s *Store) ListOwners(out *[]models.Owner) error {
-	return s.DB.Order("created_at desc").Find(out).Error
+func (s *Store) ListOwners(p Page, out *[]models.Owner) (int64, error) {
+	var total int64
+	if err := s.DB.Model(&models.Owner{}).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, p.apply(s.DB).Order("created_at desc, id").Find(out).Error
 }
 
 func (s *Store) GetOwner(id string, out *models.Owner) error {
 	return s.DB.First(out, "id = ?", id).Error
 }
 
-//
-// ===== Vehicles =====
-//
+// NormalizeRegistration svodi tablice na oblik iz registra (velika slova, bez razmaka na krajevima).
+func NormalizeRegistration(reg string) string {
+	return strings.ToUpper(strings.TrimSpace(reg))
+}
+
+func validateVehicle(v *models.Vehicle) error {
+	v.Registration = NormalizeRegistration(v.Registration)
+	v.Mark, v.Model = strings.TrimSpace(v.Mark), strings.TrimSpace(v.Model)
+	switch {
+	case v.Registration == "":
+		return fmt.Errorf("%w: registration is required", ErrInvalid)
+	case v.Mark == "" || v.Model == "":
+		return fmt.Errorf("%w: mark and model are required", ErrInvalid)
+	case v.Year != 0 && (v.Year < 1900 || v.Year > time.Now().Year()+1):
+		return fmt.Errorf("%w: invalid year", ErrInvalid)
+	}
+	return nil
+}
 
+// CreateVehicle upisuje rucno uneto (LOCAL) vozilo. Vlasnik, ako je zadat,
+// mora postojati lokalno.
 func (s *Store) CreateVehicle(v *models.Vehicle) error {
-	// ensure owner exists
+	if err := validateVehicle(v); err != nil {
+		return err
+	}
 	if v.OwnerID != "" {
-		var o models.Owner
-		if err := s.GetOwner(v.OwnerID, &o); err != nil {
+		if err := s.GetOwner(v.OwnerID, &v.Owner); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: owner %s not found", ErrInvalid, v.OwnerID)
+			}
 			return err
 		}
 	}
-	return s.DB.Create(v).Error
+	v.ID, v.Source = "", models.SourceLocal
+	return duplicate(s.DB.Omit("Owner").Create(v).Error, "vehicle with this registration")
 }
 
-func (s *Store) ListVehicles(out *[]models.Vehicle) error {
-	return s.DB.Preload("Owner").Order("created_at desc").Find(out).Error
+func (s *Store) ListVehicles(p Page, out *[]models.Vehicle) (int64, error) {
+	var total int64
+	if err := s.DB.Model(&models.Vehicle{}).Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, p.apply(s.DB.Preload("Owner")).Order("created_at desc, id").Find(out).Error
 }
 
-// func (s *Store) GetVehicle(id string, out *models.Vehicle) error {
-// 	return s.DB.Preload("Owner").First(out, "registration = ?", id).Error
-// }
+func (s *Store) GetVehicle(id string, out *models.Vehicle) error {
+	return s.DB.Preload("Owner").First(out, "id = ?", id).Error
+}
 
-func (s *Store) SearchVehicles(req models.SearchVehicleRequest, out *[]models.Vehicle) error {
-	q := s.DB.Model(&models.Vehicle{}).Preload("Owner")
+func (s *Store) SearchVehicles(req models.SearchVehicleRequest, p Page, out *[]models.Vehicle) (int64, error) {
+	q := s.DB.Model(&models.Vehicle{})
 
 	if req.Mark != "" {
 		q = q.Where("mark ILIKE ?", "%"+req.Mark+"%")
@@ -129,20 +220,27 @@ func (s *Store) SearchVehicles(req models.SearchVehicleRequest, out *[]models.Ve
 		q = q.Where("color ILIKE ?", "%"+req.Color+"%")
 	}
 	if req.Registration != "" {
-		q = q.Where("registration ILIKE ?", "%"+req.Registration+"%")
+		q = q.Where("registration ILIKE ?", "%"+strings.TrimSpace(req.Registration)+"%")
 	}
 
-	return q.Order("created_at desc").Find(out).Error
+	var total int64
+	if err := q.Count(&total).Error; err != nil {
+		return 0, err
+	}
+	return total, p.apply(q.Preload("Owner")).Order("created_at desc, id").Find(out).Error
 }
 
+// VerifyVehicle proverava vozilo prema sinhronizovanoj kopiji MUP registra
+// (kad MUP nije dostupan): vozilo je ispravno ako vlasnik ima zadati JMBG i
+// vozilo nije ukradeno. Rucno uneta (LOCAL) vozila se ne uzimaju u obzir.
 func (s *Store) VerifyVehicle(req models.VehicleVerificationRequest) (bool, *models.Vehicle, error) {
 	if req.Registration == "" || req.JMBG == "" {
-		return false, nil, errors.New("registration and jmbg are required")
+		return false, nil, fmt.Errorf("%w: registration and jmbg are required", ErrInvalid)
 	}
 
 	var v models.Vehicle
 	err := s.DB.Preload("Owner").
-		First(&v, "registration = ?", req.Registration).Error
+		First(&v, "registration = ? AND source = ?", NormalizeRegistration(req.Registration), models.SourceMup).Error
 	if err != nil {
 		return false, nil, err
 	}
@@ -160,7 +258,10 @@ func (s *Store) VerifyVehicle(req models.VehicleVerificationRequest) (bool, *mod
 // ===== Violations =====
 //
 
-func (s *Store) CreateViolation(v *models.Violation) error {
+// CreateViolation upisuje prekrsaj i u istoj transakciji izdaje kaznu po
+// tarifi koja je vazila na dan prekrsaja. fineAmount je opcion i mora biti u
+// opsegu tarife; bez njega se izdaje minimalna kazna.
+func (s *Store) CreateViolation(v *models.Violation, fineAmount *float64, fine *models.Fine) error {
 	if v.PoliceID != "" {
 		var p models.User // ← User umesto PoliceProfile
 		if err := s.GetPolice(v.PoliceID, &p); err != nil {
@@ -175,62 +276,253 @@ func (s *Store) CreateViolation(v *models.Violation) error {
 		v.Date = time.Now()
 	}
 
-	return s.DB.Create(v).Error
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		var t models.Tariff
+		if err := tariffAt(tx, v.TypeOfViolation, v.Date, &t); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("no tariff for %q on %s", v.TypeOfViolation, v.Date.Format("2006-01-02"))
+			}
+			return err
+		}
+
+		amount := t.FineMin
+		if fineAmount != nil {
+			if *fineAmount < t.FineMin || *fineAmount > t.FineMax {
+				return fmt.Errorf("%w: %.2f-%.2f", ErrFineOutOfRange, t.FineMin, t.FineMax)
+			}
+			amount = *fineAmount
+		}
+
+		v.TariffID, v.Points = t.ID, t.Points
+		v.Status, v.VoidedAt, v.VoidedBy, v.VoidReason, v.PointsReversed = models.ViolationActive, nil, "", "", false
+		if err := tx.Create(v).Error; err != nil {
+			return err
+		}
+		*fine = newFine(amount, v.Date, v.ID)
+		if err := assignPaymentReference(tx, fine); err != nil {
+			return err
+		}
+		if err := tx.Create(fine).Error; err != nil {
+			return err
+		}
+		withPayable(time.Now(), fine)
+		if err := recordStatus(tx, fine.ID, "", models.FineIssued, time.Now(), "issued with violation "+v.ID); err != nil {
+			return err
+		}
+		// poeni idu u MUP preko outbox-a, u istoj transakciji
+		return enqueuePoints(tx, v, v.Points)
+	})
 }
 
-func (s *Store) ListViolations(out *[]models.Violation) error {
-	return s.DB.Order("date desc").Find(out).Error
+var (
+	ErrViolationVoided = errors.New("violation is already voided")
+	ErrVoidReason      = errors.New("void reason is required")
+)
+
+// voidViolation ponistava zakljucan prekrsaj, otkazuje njegovu kaznu i u
+// outbox upisuje vracanje poena u MUP-u.
+func voidViolation(tx *gorm.DB, v *models.Violation, by, reason string, at time.Time) error {
+	if v.Status == models.ViolationVoided {
+		return ErrViolationVoided
+	}
+	if strings.TrimSpace(reason) == "" {
+		return ErrVoidReason
+	}
+
+	v.Status, v.VoidedAt, v.VoidedBy, v.VoidReason = models.ViolationVoided, &at, by, reason
+	if err := tx.Model(v).Select("Status", "VoidedAt", "VoidedBy", "VoidReason").Updates(v).Error; err != nil {
+		return err
+	}
+
+	if !v.PointsReversed {
+		if err := enqueuePoints(tx, v, -v.Points); err != nil {
+			return err
+		}
+	}
+
+	var f models.Fine
+	res := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("violation_id = ?", v.ID).Limit(1).Find(&f)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return res.Error
+	}
+	if f.Status == models.FineCancelled {
+		return nil
+	}
+	return changeStatus(tx, &f, models.FineCancelled, at, "violation voided: "+reason)
+}
+
+// VoidViolation ponistava prekrsaj (razlog je obavezan), otkazuje kaznu i
+// zatvara otvorenu zalbu na njega kao CLOSED, bez recenzenta i odluke.
+func (s *Store) VoidViolation(id, by, reason string, out *models.Violation) error {
+	return s.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(out, "id = ?", id).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if err := voidViolation(tx, out, by, reason, now); err != nil {
+			return err
+		}
+		return tx.Model(&models.Appeal{}).
+			Where("violation_id = ? AND status IN ?", id, []models.AppealStatus{models.AppealSubmitted, models.AppealUnderReview}).
+			Updates(map[string]any{
+				"status":   models.AppealClosed,
+				"decision": "violation voided: " + reason,
+			}).Error
+	})
+}
+
+// violationStatus filtrira po statusu; prazan status znaci svi prekrsaji.
+func violationStatus(q *gorm.DB, status models.ViolationStatus) *gorm.DB {
+	if status == "" {
+		return q
+	}
+	return q.Where("status = ?", status)
+}
+
+func (s *Store) ListViolations(status models.ViolationStatus, out *[]models.Violation) error {
+	return violationStatus(s.DB, status).Order("date desc").Find(out).Error
 }
 
 func (s *Store) GetViolation(id string, out *models.Violation) error {
 	return s.DB.First(out, "id = ?", id).Error
 }
 
-func (s *Store) ListViolationsByDriver(driverId string, out *[]models.Violation) error {
-	return s.DB.Where("driver_id = ?", driverId).Order("date desc").Find(out).Error
+func (s *Store) ListViolationsByDriver(driverId string, status models.ViolationStatus, out *[]models.Violation) error {
+	return violationStatus(s.DB, status).Where("driver_id = ?", driverId).Order("date desc").Find(out).Error
+}
+
+//
+// ===== Tariffs =====
+//
+
+var ErrTariffInPast = errors.New("tariff cannot take effect in the past")
+
+// tariffAt vraca verziju tarife koja je vazila u trenutku at.
+func tariffAt(db *gorm.DB, typ models.TypeOfViolation, at time.Time, out *models.Tariff) error {
+	return db.Where("type_of_violation = ? AND effective_from <= ?", typ, at).
+		Order("effective_from desc").
+		First(out).Error
+}
+
+func (s *Store) TariffAt(typ models.TypeOfViolation, at time.Time, out *models.Tariff) error {
+	return tariffAt(s.DB, typ, at, out)
+}
+
+func (s *Store) ListTariffs(out *[]models.Tariff) error {
+	return s.DB.Order("type_of_violation, effective_from desc").Find(out).Error
+}
+
+// CreateTariff dodaje novu verziju tarife. Ne sme da pocne u proslosti, da
+// vec izdati prekrsaji ostanu vezani za tarifu po kojoj su izdati.
+func (s *Store) CreateTariff(t *models.Tariff) error {
+	switch t.TypeOfViolation {
+	case models.ViolationMinor, models.ViolationMajor, models.ViolationCritical:
+	default:
+		return fmt.Errorf("unknown violation type %q", t.TypeOfViolation)
+	}
+	if t.Points < 0 || t.RiskWeight < 0 || t.FineMin < 0 || t.FineMin > t.FineMax {
+		return errors.New("points and riskWeight must be >= 0 and 0 <= fineMin <= fineMax")
+	}
+	if t.EffectiveFrom.Before(time.Now()) {
+		return ErrTariffInPast
+	}
+	return s.DB.Create(t).Error
+}
+
+// DriverRiskScore sabira tezine rizika aktivnih prekrsaja vozaca, svaku po
+// tarifi po kojoj je prekrsaj izdat. Ponisteni prekrsaji se ne racunaju.
+func (s *Store) DriverRiskScore(driverID string) (score int, count int64, err error) {
+	var row struct {
+		Score int
+		Count int64
+	}
+	err = s.DB.Raw(`
+SELECT COALESCE(SUM(t.risk_weight), 0) AS score, COUNT(v.id) AS count
+FROM violations v
+LEFT JOIN tariffs t ON t.id = v.tariff_id
+WHERE v.driver_id = ? AND v.status <> ?`, driverID, models.ViolationVoided).Scan(&row).Error
+	return row.Score, row.Count, err
 }
 
 //
-// ===== Ownership transfers =====
+// ===== Fines =====
 //
 
-func (s *Store) CreateTransfer(t *models.OwnershipTransfer) error {
-	if t.VehicleID == "" || t.OwnerOldID == "" || t.OwnerNewID == "" {
-		return errors.New("vehicleId, ownerOldId, ownerNewId are required")
+var (
+	ErrFineAlreadyPaid = errors.New("fine is already paid")
+	ErrFineOutOfRange  = errors.New("fine amount outside tariff range")
+	ErrFineCancelled   = errors.New("fine is cancelled")
+)
+
+func (s *Store) GetFine(id string, out *models.Fine) error {
+	if err := s.DB.First(out, "id = ?", id).Error; err != nil {
+		return err
+	}
+	withPayable(time.Now(), out)
+	return nil
+}
+
+// ListFinesByDriver vraca kazne za sve prekrsaje vozaca.
+func (s *Store) ListFinesByDriver(driverID string, out *[]models.Fine) error {
+	if err := s.DB.
+		Joins("JOIN violations v ON v.id = fines.violation_id").
+		Where("v.driver_id = ?", driverID).
+		Order("fines.date desc").
+		Find(out).Error; err != nil {
+		return err
+	}
+	now := time.Now()
+	for i := range *out {
+		withPayable(now, &(*out)[i])
 	}
-	if t.DateOfTransfer.IsZero() {
-		t.DateOfTransfer = time.Now()
+	return nil
+}
+
+func (s *Store) GetFineByViolation(violationID string, out *models.Fine) error {
+	if err := s.DB.First(out, "violation_id = ?", violationID).Error; err != nil {
+		return err
 	}
+	withPayable(time.Now(), out)
+	return nil
+}
 
+// PayFine oznacava kaznu placenom po iznosu koji trenutno treba uplatiti;
+// placena kazna se ne moze platiti ponovo.
+func (s *Store) PayFine(id string, out *models.Fine) error {
 	return s.DB.Transaction(func(tx *gorm.DB) error {
-		var veh models.Vehicle
 		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
-			First(&veh, "id = ?", t.VehicleID).Error; err != nil {
-			return err
-		}
-
-		// ensure owners exist
-		var oldO, newO models.Owner
-		if err := tx.First(&oldO, "id = ?", t.OwnerOldID).Error; err != nil {
+			First(out, "id = ?", id).Error; err != nil {
 			return err
 		}
-		if err := tx.First(&newO, "id = ?", t.OwnerNewID).Error; err != nil {
-			return err
+		if out.IsPaid {
+			return ErrFineAlreadyPaid
 		}
-
-		if err := tx.Create(t).Error; err != nil {
-			return err
+		if out.Status == models.FineCancelled {
+			return ErrFineCancelled
 		}
 
-		veh.OwnerID = t.OwnerNewID
-		return tx.Save(&veh).Error
+		now := time.Now()
+		return markPaid(tx, out, PayableAmount(out, now), now, "paid manually")
 	})
 }
 
-func (s *Store) ListTransfers(out *[]models.OwnershipTransfer) error {
-	return s.DB.
-		Preload("Vehicle").Preload("Vehicle.Owner").
-		Preload("OwnerOld").Preload("OwnerNew").
-		Order("date_of_transfer desc").
-		Find(out).Error
+// FineBalance racuna ukupan iznos koji vozac trenutno duguje (sa popustom
+// ili uvecanjem).
+func (s *Store) FineBalance(driverID string, out *models.FineBalance) error {
+	var unpaid []models.Fine
+	if err := s.DB.
+		Joins("JOIN violations v ON v.id = fines.violation_id").
+		Where("v.driver_id = ? AND fines.is_paid = false AND fines.status <> ?", driverID, models.FineCancelled).
+		Find(&unpaid).Error; err != nil {
+		return err
+	}
+
+	now := time.Now()
+	*out = models.FineBalance{DriverID: driverID, UnpaidCount: int64(len(unpaid))}
+	for i := range unpaid {
+		out.Outstanding += PayableAmount(&unpaid[i], now)
+	}
+	out.Outstanding = math.Round(out.Outstanding*100) / 100
+	return nil
 }
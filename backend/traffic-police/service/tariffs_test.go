@@ -0,0 +1,40 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"common/dbtest"
+	"traffic-police/models"
+)
+
+func TestDriverRiskScoreUsesChargedTariff(t *testing.T) {
+	db := dbtest.Open(t, "", &models.Tariff{}, &models.Violation{})
+	s := NewStore(db)
+	day := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	tariff := func(id string, from time.Time, weight int) *models.Tariff {
+		return &models.Tariff{BaseModel: models.BaseModel{ID: id}, TypeOfViolation: models.ViolationMajor, EffectiveFrom: from, RiskWeight: weight}
+	}
+	violation := func(id, tariffID string, status models.ViolationStatus) *models.Violation {
+		return &models.Violation{BaseModel: models.BaseModel{ID: id}, DriverID: "D1", TypeOfViolation: models.ViolationMajor,
+			Date: day, TariffID: tariffID, Status: status}
+	}
+	dbtest.Insert(t, db,
+		tariff("T-OLD", day.AddDate(-1, 0, 0), 5),
+		// tarifa dodata kasnije sa ranijim datumom ne menja rizik vec
+		// izdatih prekrsaja
+		tariff("T-BACKDATED", day.AddDate(0, 0, -1), 500),
+		violation("V1", "T-OLD", models.ViolationActive),
+		violation("V2", "T-OLD", models.ViolationActive),
+		violation("V3", "T-OLD", models.ViolationVoided),
+	)
+
+	score, count, err := s.DriverRiskScore("D1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score != 10 || count != 2 {
+		t.Fatalf("score = %d over %d violation(s), want 10 over 2", score, count)
+	}
+}
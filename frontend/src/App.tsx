@@ -17,6 +17,7 @@ import MupVehiclesPage from "./pages/MupVehicles";
 import ChecksPage from "./pages/ChecksPage";
 import MyViolationsPage from "./pages/MyViolationsPage";
 import RequireRole from "./api/RequireRole";
+import { authApi } from "./api/queries";
 
 function getStoredUser() {
   const email = localStorage.getItem("email");
@@ -87,7 +88,10 @@ export default function App() {
   const [user, setUser] = useState<string | null>(() => getStoredUser());
 
   const logout = () => {
+    const refreshToken = localStorage.getItem("refreshToken");
+    if (refreshToken) authApi.logout(refreshToken).catch(() => {});
     localStorage.removeItem("accessToken");
+    localStorage.removeItem("refreshToken");
     localStorage.removeItem("email");
     setUser(null);
   };
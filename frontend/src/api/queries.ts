@@ -1,19 +1,58 @@
+import type { MeResponse } from "../types/api"
+
 const API_BASE = import.meta.env.VITE_API_URL || "http://localhost:8000"
 
-async function apiFetch<T>(url: string, options?: RequestInit): Promise<T> {
-  const response = await fetch(`${API_BASE}${url}`, {
+function doFetch(url: string, options?: RequestInit): Promise<Response> {
+  const token = localStorage.getItem("accessToken")
+
+  return fetch(`${API_BASE}${url}`, {
+    ...options,
     headers: {
       "Content-Type": "application/json",
+      ...(token ? { Authorization: `Bearer ${token}` } : {}),
       ...options?.headers,
     },
-    ...options,
   })
+}
+
+// refresh rotira refresh token; vraca false ako sesija vise ne vazi
+async function refreshSession(): Promise<boolean> {
+  const refreshToken = localStorage.getItem("refreshToken")
+  if (!refreshToken) return false
+
+  const response = await fetch(`${API_BASE}/api/auth/refresh`, {
+    method: "POST",
+    headers: { "Content-Type": "application/json" },
+    body: JSON.stringify({ refresh_token: refreshToken }),
+  })
+  if (!response.ok) {
+    localStorage.removeItem("accessToken")
+    localStorage.removeItem("refreshToken")
+    return false
+  }
+
+  const data = (await response.json()) as LoginResponse
+  if (data.access_token) localStorage.setItem("accessToken", data.access_token)
+  if (data.refresh_token) localStorage.setItem("refreshToken", data.refresh_token)
+  return true
+}
+
+async function apiFetch<T>(url: string, options?: RequestInit): Promise<T> {
+  let response = await doFetch(url, options)
+
+  // istekao access token: probaj jednom sa novim
+  if (response.status === 401 && !url.startsWith("/api/auth/") && (await refreshSession())) {
+    response = await doFetch(url, options)
+  }
 
   if (!response.ok) {
     const text = await response.text()
     throw new Error(text || "API error")
   }
 
+  // logout vraca 204 bez tela
+  if (response.status === 204) return undefined as T
+
   // login može da vraća plain json; ako nekad vraća prazno, handle-uj ovde
   return response.json()
 }
@@ -39,6 +78,8 @@ export type LoginRequest = {
 }
 
 export type LoginResponse = {
+  access_token?: string
+  refresh_token?: string
   accessToken?: string
   refreshToken?: string
   token?: string
@@ -58,6 +99,14 @@ export const authApi = {
       method: "POST",
       body: JSON.stringify(data),
     }),
+
+  me: () => apiFetch<MeResponse>(`/api/auth/me`),
+
+  logout: (refreshToken: string) =>
+    apiFetch<void>(`/api/auth/logout`, {
+      method: "POST",
+      body: JSON.stringify({ refresh_token: refreshToken }),
+    }),
 }
 
 //
@@ -79,11 +128,34 @@ export const mupVehiclesApi = {
   // drivers
   getDrivers: () => apiFetch(`/api/mup-vehicles/drivers`),
   getDriverById: (id: string) => apiFetch(`/api/mup-vehicles/drivers/${id}`),
+  getDriverPointsHistory: (id: string) => apiFetch<any[]>(`/api/mup-vehicles/drivers/${id}/points/history`),
+
+  // gradjanin: vozila vlasnika iz tokena
+  getMyVehicles: () => apiFetch<any[]>(`/api/mup-vehicles/me/vehicles`),
 
   // owners / transfers / admins
   getOwners: () => apiFetch(`/api/mup-vehicles/owners`),
-  getTransfers: () => apiFetch(`/api/mup-vehicles/transfers`),
+  getTransfers: (status = "") =>
+    apiFetch(`/api/mup-vehicles/transfers${status ? `?status=${status}` : ""}`),
   getAdmins: () => apiFetch(`/api/mup-vehicles/admins`),
+
+  // prenos vlasnistva: prodavac podnosi zahtev, obe strane potvrdjuju
+  getTransfer: (id: string) => apiFetch<any>(`/api/mup-vehicles/transfers/${id}`),
+  requestTransfer: (registration: string, buyerJmbg: string) =>
+    apiFetch<any>(`/api/mup-vehicles/transfers`, {
+      method: "POST",
+      body: JSON.stringify({ registration, buyerJmbg }),
+    }),
+  confirmTransfer: (id: string) =>
+    apiFetch<any>(`/api/mup-vehicles/transfers/${id}/confirm`, { method: "PATCH" }),
+  cancelTransfer: (id: string, reason = "") =>
+    apiFetch<any>(`/api/mup-vehicles/transfers/${id}/cancel`, {
+      method: "PATCH",
+      body: JSON.stringify({ reason }),
+    }),
+  getVehicleTransfers: (registration: string) =>
+    apiFetch<any[]>(`/api/mup-vehicles/vehicles/${encodeURIComponent(registration)}/transfers`),
+  getMyTransfers: () => apiFetch<any[]>(`/api/mup-vehicles/me/transfers`),
 };
 //
 // ======================
@@ -92,29 +164,101 @@ export const mupVehiclesApi = {
 //
 
 export const authAdminApi = {
-  getUsers: () => apiFetch(`/api/auth/users`),
+  getUsers: (q = "", page = 1) =>
+    apiFetch(`/api/auth/users?q=${encodeURIComponent(q)}&page=${page}`),
 
   setUserRole: (id: string, role: "CITIZEN" | "MUP" | "TRAFFIC") =>
     apiFetch(`/api/auth/users/${id}/role`, {
       method: "PATCH",
       body: JSON.stringify({ role }),
     }),
+
+  deactivateUser: (id: string) =>
+    apiFetch(`/api/auth/users/${id}/deactivate`, { method: "PATCH" }),
+  activateUser: (id: string) =>
+    apiFetch(`/api/auth/users/${id}/activate`, { method: "PATCH" }),
 };
 
 export const trafficPoliceApi = {
   // ===== Violations =====
-  getViolations: () => apiFetch<any[]>(`/api/traffic-police/violations`),
+  getViolations: (status = "") =>
+    apiFetch<any[]>(`/api/traffic-police/violations${status ? `?status=${status}` : ""}`),
   getViolationById: (id: string) => apiFetch<any>(`/api/traffic-police/violations/${id}`),
   createViolation: (data: any) =>
     apiFetch<any>(`/api/traffic-police/violations`, { method: "POST", body: JSON.stringify(data) }),
-  getViolationsByDriver: (driverId: string) =>
-    apiFetch<any[]>(`/api/traffic-police/violations/driver/${driverId}`),
+  getViolationsByDriver: (driverId: string, status = "") =>
+    apiFetch<any[]>(`/api/traffic-police/violations/driver/${driverId}${status ? `?status=${status}` : ""}`),
+  voidViolation: (id: string, reason: string) =>
+    apiFetch<any>(`/api/traffic-police/violations/${id}/void`, {
+      method: "PATCH",
+      body: JSON.stringify({ reason }),
+    }),
+
+  // ===== Appeals =====
+  createAppeal: (violationId: string, data: { reason: string; attachments?: { name: string; url: string }[] }) =>
+    apiFetch<any>(`/api/traffic-police/violations/${violationId}/appeals`, {
+      method: "POST",
+      body: JSON.stringify(data),
+    }),
+  getAppeals: (status = "") =>
+    apiFetch<any[]>(`/api/traffic-police/appeals${status ? `?status=${status}` : ""}`),
+  getAppeal: (id: string) => apiFetch<any>(`/api/traffic-police/appeals/${id}`),
+  startAppealReview: (id: string) =>
+    apiFetch<any>(`/api/traffic-police/appeals/${id}/review`, { method: "PATCH" }),
+  decideAppeal: (id: string, accept: boolean, note = "") =>
+    apiFetch<any>(`/api/traffic-police/appeals/${id}/decision`, {
+      method: "PATCH",
+      body: JSON.stringify({ accept, note }),
+    }),
+  getMyAppeals: () => apiFetch<any[]>(`/api/traffic-police/me/appeals`),
+
+  // ===== Outbox =====
+  getStuckOutbox: () => apiFetch<any[]>(`/api/traffic-police/outbox/stuck`),
+  getMupStats: () => apiFetch<any>(`/api/traffic-police/mup/stats`),
+  // kopija MUP registra; full=true preuzima ceo registar
+  getRegistrySync: () => apiFetch<any>(`/api/traffic-police/registry/sync`),
+  runRegistrySync: (full = false) =>
+    apiFetch<any>(`/api/traffic-police/registry/sync${full ? "?full=true" : ""}`, { method: "POST" }),
+
+  // ===== Tariffs =====
+  getTariffs: () => apiFetch<any[]>(`/api/traffic-police/tariffs`),
+  createTariff: (data: any) =>
+    apiFetch<any>(`/api/traffic-police/tariffs`, { method: "POST", body: JSON.stringify(data) }),
+
+  // ===== Fines =====
+  getFinesByDriver: (driverId: string) =>
+    apiFetch<any[]>(`/api/traffic-police/fines/driver/${driverId}`),
+  getFineBalance: (driverId: string) =>
+    apiFetch<{ driverId: string; outstanding: number; unpaidCount: number }>(
+      `/api/traffic-police/fines/driver/${driverId}/balance`
+    ),
+  getFineByViolation: (violationId: string) =>
+    apiFetch<any>(`/api/traffic-police/fines/violation/${violationId}`),
+  payFine: (id: string) =>
+    apiFetch<any>(`/api/traffic-police/fines/${id}/pay`, { method: "PATCH" }),
+  getFine: (id: string) => apiFetch<any>(`/api/traffic-police/fines/${id}`),
+  getFineHistory: (id: string) => apiFetch<any[]>(`/api/traffic-police/fines/${id}/history`),
+  getPaymentSlip: (id: string) =>
+    apiFetch<any>(`/api/traffic-police/fines/${id}/payment-slip`),
+  // izvod banke kao CSV: reference,amount[,date]
+  importStatement: (csv: string) =>
+    apiFetch<any>(`/api/traffic-police/fines/payments/import`, {
+      method: "POST",
+      headers: { "Content-Type": "text/csv" },
+      body: csv,
+    }),
+
+  // gradjanin: prekrsaji i kazne vozaca iz tokena
+  getMyViolations: () => apiFetch<any[]>(`/api/traffic-police/me/violations`),
+  getMyFines: () => apiFetch<any[]>(`/api/traffic-police/me/fines`),
 
   // ===== NEW: Driver Report (This was missing!) =====
   getDriverReport: (driverId: string) =>
     apiFetch<{ totalViolations: number; riskLevel: string }>(
       `/api/traffic-police/drivers/${driverId}/report`
     ),
+  getDriverSuspensions: (driverId: string) =>
+    apiFetch<any[]>(`/api/traffic-police/drivers/${driverId}/suspensions`),
 
   // ===== Police =====
   getPolice: () => apiFetch<any[]>(`/api/traffic-police/police`),
@@ -128,27 +272,23 @@ export const trafficPoliceApi = {
     apiFetch<any>(`/api/traffic-police/police/${id}/downgrade-rank`, { method: "PATCH" }),
 
   // ===== Owners =====
-  getOwners: () => apiFetch<any[]>(`/api/traffic-police/owners`),
+  // liste su stranicene; ukupan broj je u X-Total-Count
+  getOwners: (page = 1) => apiFetch<any[]>(`/api/traffic-police/owners?page=${page}`),
   getOwnerById: (id: string) => apiFetch<any>(`/api/traffic-police/owners/${id}`),
   createOwner: (data: any) =>
     apiFetch<any>(`/api/traffic-police/owners`, { method: "POST", body: JSON.stringify(data) }),
 
   // ===== Vehicles =====
-  getVehicles: () => apiFetch<any[]>(`/api/traffic-police/vehicles`),
+  getVehicles: (page = 1) => apiFetch<any[]>(`/api/traffic-police/vehicles?page=${page}`),
   getVehicleById: (id: string) => apiFetch<any>(`/api/traffic-police/vehicles/${id}`),
   createVehicle: (data: any) =>
     apiFetch<any>(`/api/traffic-police/vehicles`, { method: "POST", body: JSON.stringify(data) }),
-  searchVehicles: (data: any) =>
-    apiFetch<any>(`/api/traffic-police/vehicles/search`, { method: "POST", body: JSON.stringify(data) }),
+  searchVehicles: (data: any, page = 1) =>
+    apiFetch<any[]>(`/api/traffic-police/vehicles/search?page=${page}`, { method: "POST", body: JSON.stringify(data) }),
   verifyVehicle: (data: any) =>
-    apiFetch<{ valid: boolean; reason?: string }>(`/api/traffic-police/vehicles/verify`, {
+    apiFetch<{ valid: boolean; reason?: string; offline?: boolean; syncedAt?: string }>(`/api/traffic-police/vehicles/verify`, {
       method: "POST",
       body: JSON.stringify(data)
     }),
-
-  // ===== Transfers =====
-  getTransfers: () => apiFetch<any[]>(`/api/traffic-police/transfers`),
-  createTransfer: (data: any) =>
-    apiFetch<any>(`/api/traffic-police/transfers`, { method: "POST", body: JSON.stringify(data) }),
 };
 
@@ -70,8 +70,13 @@ export default function LoginPage({ onLogin }: Props) {
         password,
       });
 
-      const token = (res as LoginResponse)?.accessToken || (res as LoginResponse)?.token;
+      const token =
+        (res as LoginResponse)?.access_token ||
+        (res as LoginResponse)?.accessToken ||
+        (res as LoginResponse)?.token;
       if (token) localStorage.setItem("accessToken", token);
+      const refreshToken = res.refresh_token || res.refreshToken;
+      if (refreshToken) localStorage.setItem("refreshToken", refreshToken);
 
       localStorage.setItem("email", email.trim());
       localStorage.setItem("role", res.role);
@@ -358,16 +358,18 @@ export default function MupVehiclesPage() {
             <Card title={`Prenosi vlasništva (${transfers.length})`}>
               {transfers.length === 0 ? (
                 <div className="rounded-2xl border border-slate-800 bg-slate-900/40 p-4 text-sm text-slate-400">
-                  Nema transfera (seed trenutno ne puni).
+                  Nema transfera.
                 </div>
               ) : (
                 <div className="grid gap-3">
                   {transfers.map((t) => (
                     <div key={t.id} className="rounded-2xl border border-slate-800 bg-slate-900/40 p-4">
-                      <p className="text-sm font-semibold">{t.vehicle?.registration}</p>
+                      <p className="text-sm font-semibold">
+                        {t.vehicle?.registration} <span className="text-xs text-slate-400">{t.status}</span>
+                      </p>
                       <p className="mt-1 text-xs text-slate-300">
-                        Old: {t.ownerOld?.firstName} {t.ownerOld?.lastName} → New:{" "}
-                        {t.ownerNew?.firstName} {t.ownerNew?.lastName}
+                        Old: {t.oldOwner?.firstName} {t.oldOwner?.lastName} → New:{" "}
+                        {t.newOwner?.firstName} {t.newOwner?.lastName}
                       </p>
                       <Mono>{t.id}</Mono>
                     </div>
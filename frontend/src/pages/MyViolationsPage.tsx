@@ -1,19 +1,71 @@
-// import { formatViolation } from "../types/api"
+import { useEffect, useState } from "react"
+import { trafficPoliceApi } from "../api/queries"
+import { type Fine, type Violation, formatViolation } from "../types/api"
+
+function fmtDate(iso: string) {
+  const d = new Date(iso)
+  if (Number.isNaN(d.getTime())) return iso
+  return d.toLocaleString()
+}
 
 export default function MyViolationsPage() {
-//   const { data: violations } = useMyViolations()
+  const [violations, setViolations] = useState<Violation[]>([])
+  const [fines, setFines] = useState<Fine[]>([])
+  const [loading, setLoading] = useState(true)
+  const [error, setError] = useState<string | null>(null)
+
+  useEffect(() => {
+    let cancelled = false
+
+    // backend uzima ID vozacke iz tokena, korisnik ga ne unosi
+    Promise.all([trafficPoliceApi.getMyViolations(), trafficPoliceApi.getMyFines()])
+      .then(([v, f]) => {
+        if (cancelled) return
+        setViolations(v ?? [])
+        setFines(f ?? [])
+      })
+      .catch((e: any) => {
+        if (!cancelled) setError(e?.message ?? "Greška pri učitavanju")
+      })
+      .finally(() => {
+        if (!cancelled) setLoading(false)
+      })
+
+    return () => {
+      cancelled = true
+    }
+  }, [])
+
+  const fineFor = (violationId: string) => fines.find(f => f.violationId === violationId)
 
   return (
     <div className="space-y-4">
-      {/* <h1 className="text-xl font-semibold">Moji prekršaji</h1>
-
-      {violations.map(v => (
-        <div key={v.id} className="p-4 border rounded-lg">
-          <p className="font-medium">{formatViolation(v.type)}</p>
-          <p className="text-sm text-slate-500">{v.description}</p>
-          <p className="text-xs text-slate-400">Status: {v.status}</p>
-        </div>
-      ))} */}
+      <h1 className="text-xl font-semibold">Moji prekršaji</h1>
+
+      {loading && <p className="text-sm text-slate-500">Učitavanje...</p>}
+      {error && <p className="text-sm text-red-600">{error}</p>}
+      {!loading && !error && violations.length === 0 && (
+        <p className="text-sm text-slate-500">Nemate evidentiranih prekršaja.</p>
+      )}
+
+      {violations.map(v => {
+        const fine = fineFor(v.id)
+        return (
+          <div key={v.id} className="p-4 border rounded-lg">
+            <p className="font-medium">{formatViolation(v.typeOfViolation)}</p>
+            <p className="text-sm text-slate-500">{v.location}</p>
+            <p className="text-xs text-slate-400">{fmtDate(v.date)}</p>
+            {fine && (
+              <p className="text-xs text-slate-500">
+                Kazna: {fine.amount} RSD ·{" "}
+                {fine.isPaid
+                  ? "plaćena"
+                  : `za uplatu ${fine.payableAmount ?? fine.amount} RSD${fine.status === "OVERDUE" ? " (istekao rok)" : ""}`}
+              </p>
+            )}
+          </div>
+        )
+      })}
     </div>
   )
-}
\ No newline at end of file
+}
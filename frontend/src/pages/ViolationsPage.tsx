@@ -24,7 +24,7 @@ type CreateViolationResponse =
     violation: Violation;
     vehicle?: any;
     driver?: any;
-    warning?: string;
+    pointsUpdate?: "PENDING" | "DELIVERED";
   };
 
 export default function ViolationsPage() {
@@ -183,12 +183,12 @@ export default function ViolationsPage() {
       const created: Violation =
         (res as any)?.violation ? (res as any).violation : (res as Violation);
 
-      const warning = (res as any)?.warning as string | undefined;
+      const pointsUpdate = (res as any)?.pointsUpdate as string | undefined;
 
       setViolations((prev) => [created, ...prev]);
       setSelected(created);
 
-      if (warning) setSuccess(`Kreirano, ali: ${warning}`);
+      if (pointsUpdate === "PENDING") setSuccess("Prekršaj kreiran. Poeni vozača će biti ažurirani naknadno.");
       else setSuccess("Prekršaj kreiran. Poeni vozača su ažurirani.");
 
       // reset form
@@ -35,6 +35,23 @@ export type LoginResponse = {
   user?: any
 }
 
+export type AuthUser = BaseModel & {
+  email: string
+  firstName: string
+  lastName: string
+  role: string
+  isActive: boolean
+  mupOwnerId?: string
+  mupJmbg?: string
+  mupDriverId?: string
+}
+
+// GET /api/auth/me
+export type MeResponse = {
+  user: AuthUser
+  driver: Driver | null
+}
+
 // ======================
 // MUP VEHICLES SERVICE
 // ======================
@@ -73,14 +90,21 @@ export type Vehicle = BaseModel & {
   owner: Owner
 }
 
+export type TransferStatus = "PENDING" | "COMPLETED" | "CANCELLED"
+
 export type OwnershipTransfer = BaseModel & {
   vehicleId?: UUID
   vehicle: Vehicle
-  ownerOldId?: UUID
-  ownerOld: Owner
-  ownerNewId?: UUID
-  ownerNew: Owner
-  dateOfTransfer: string
+  oldOwnerId?: UUID
+  oldOwner: Owner
+  newOwnerId?: UUID
+  newOwner: Owner
+  status: TransferStatus
+  sellerConfirmedAt?: string
+  buyerConfirmedAt?: string
+  dateOfTransfer?: string
+  cancelledAt?: string
+  cancelReason?: string
 }
 
 // ======================
@@ -123,12 +147,49 @@ export type Violation = BaseModel & {
   driverId: UUID | string
   vehicleId: UUID | string
   policeId: UUID | string
+  tariffId?: string
+  points?: number
+  status?: "ACTIVE" | "VOIDED"
+  voidedAt?: string
+  voidedBy?: string
+  voidReason?: string
+  pointsReversed?: boolean
+}
+
+export type AppealStatus = "SUBMITTED" | "UNDER_REVIEW" | "ACCEPTED" | "REJECTED" | "CLOSED"
+
+export type Appeal = BaseModel & {
+  violationId: string
+  driverId: string
+  submittedBy: string
+  reason: string
+  attachments: { name: string; url: string }[]
+  status: AppealStatus
+  reviewerId?: string
+  decision?: string
+  reviewedAt?: string
+}
+
+export type Tariff = BaseModel & {
+  typeOfViolation: TypeOfViolation
+  effectiveFrom: string
+  points: number
+  fineMin: number
+  fineMax: number
+  riskWeight: number
 }
 
 export type Fine = BaseModel & {
   amount: number
   isPaid: boolean
+  paidAt?: string
   date: string
+  paymentReference?: string
+  status?: "ISSUED" | "PAID" | "OVERDUE" | "CANCELLED"
+  discountUntil?: string
+  dueDate?: string
+  payableAmount?: number
+  paidAmount?: number
   violationId: UUID
 }
 